@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"text/tabwriter"
+
+	clangformat "github.com/javorszky/go-diff-clang/pkg/clang-format"
+)
+
+// compare scores two configs against the same corpora and shows how their
+// costs, keys and output differ.
+func compare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	corpusFlags := addCorpusFlags(fs)
+
+	var (
+		maxCosts   = fs.Int("costs", 20, "how many per-file costs to list, the biggest differences first")
+		maxFiles   = fs.Int("files", 5, "how many of the files formatted differently to show, the most changed first")
+		maxHunks   = fs.Int("hunks", 3, "how many hunks to show of each file, the largest first")
+		outputFile = fs.String("output", "", "also write the costs and the keys that differ to this file as JSON")
+	)
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s compare [flags] <a.clang-format> <b.clang-format>\n\n"+
+			"Formats the corpus with both configs and shows their total and per-file costs side by side, the\n"+
+			"keys they set differently, and the hunks where their output differs.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	fileA, fileB := fs.Arg(0), fs.Arg(1)
+
+	err := checkCounts(fs, "costs", "files", "hunks")
+	if err != nil {
+		return err
+	}
+
+	cfg, err := corpusFlags.config()
+	if err != nil {
+		return err
+	}
+
+	a, err := clangformat.LoadClangFormat(fileA)
+	if err != nil {
+		return err
+	}
+
+	b, err := clangformat.LoadClangFormat(fileB)
+	if err != nil {
+		return err
+	}
+
+	ctx := interruptContext()
+
+	cleanup, err := corpusFlags.prepare(ctx, &cfg)
+	defer cleanup()
+	if err != nil {
+		return err
+	}
+
+	result, err := clangformat.Compare(ctx, cfg, a, b)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s has a %s cost of %s, %s one of %s (%+d).\n\n", fileA, cfg.Metric.Name(),
+		describeTotal(result.A.Total, cfg), fileB, describeTotal(result.B.Total, cfg),
+		result.B.Total.Weighted-result.A.Total.Weighted)
+
+	printComparison(os.Stdout, result, len(cfg.Corpora) > 1, *maxCosts)
+
+	if len(result.Files) == 0 {
+		fmt.Printf("\nboth configs format the corpus identically.\n")
+	} else {
+		fmt.Println()
+		printFileDiffs(os.Stdout, result.Files, fileA, fileB, len(cfg.Corpora) > 1, *maxFiles, *maxHunks)
+	}
+
+	if *outputFile != "" {
+		raw, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(*outputFile, append(raw, '\n'), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printComparison lists the keys that differ and the files whose cost
+// differs the most.
+func printComparison(w io.Writer, result *clangformat.Comparison, corpora bool, maxCosts int) {
+	if len(result.Keys) == 0 {
+		fmt.Fprintf(w, "the configs set every key the same way.\n")
+	} else {
+		fmt.Fprintf(w, "%d keys differ:\n\n", len(result.Keys))
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "key\ta\tb\n")
+		for _, k := range result.Keys {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", k.Key, orUnset(k.A), orUnset(k.B))
+		}
+		tw.Flush()
+	}
+
+	shown := result.Costs[:min(maxCosts, len(result.Costs))]
+	if len(shown) < len(result.Costs) {
+		fmt.Fprintf(w, "\n%d files changed by either config, the %d whose cost differs the most:\n\n",
+			len(result.Costs), len(shown))
+	} else {
+		fmt.Fprintf(w, "\n%d files changed by either config:\n\n", len(result.Costs))
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "a\tb\tdelta\t\t\n")
+	for _, f := range shown {
+		name := f.Path
+		if corpora {
+			name = path.Join(f.Corpus, f.Path)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t\t%s\n", formatCost(f.A), formatCost(f.B), fmt.Sprintf("%+g", f.Delta()), name)
+	}
+	tw.Flush()
+}
+
+func orUnset(value string) string {
+	if value == "" {
+		return "(unset)"
+	}
+
+	return value
+}
+
+// formatCost prints a file cost without trailing zeros.
+func formatCost(cost float64) string {
+	return strconv.FormatFloat(cost, 'f', -1, 64)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+
+	clangformat "github.com/javorszky/go-diff-clang/pkg/clang-format"
+	"github.com/javorszky/go-diff-clang/pkg/diff"
+)
+
+// explain formats the corpora with two values of an option on top of a
+// config and shows where the outputs differ.
+func explain(args []string) error {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	corpusFlags := addCorpusFlags(fs)
+
+	var (
+		configFile = fs.String("config", ".clang-format-ideal", "the .clang-format file to set the option on")
+		maxFiles   = fs.Int("files", 5, "how many of the files that differ to show, the most changed first")
+		maxHunks   = fs.Int("hunks", 3, "how many hunks to show of each file, the largest first")
+	)
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s explain [flags] <option> <value> <other value>\n\n"+
+			"Formats the corpus with -config, the option set to either value, and shows the files and hunks\n"+
+			"where the two outputs differ, the biggest differences first.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 3 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	option, from, to := fs.Arg(0), fs.Arg(1), fs.Arg(2)
+
+	err := checkCounts(fs, "files", "hunks")
+	if err != nil {
+		return err
+	}
+
+	cfg, err := corpusFlags.config()
+	if err != nil {
+		return err
+	}
+
+	format, err := clangformat.LoadClangFormat(*configFile)
+	if err != nil {
+		return err
+	}
+
+	known := clangformat.KnownValues(option)
+	for _, value := range []string{from, to} {
+		if !slices.Contains(known, value) {
+			slog.Warn("the search does not try this value, clang-format may reject it", "option", option,
+				"value", value)
+		}
+	}
+
+	ctx := interruptContext()
+
+	cleanup, err := corpusFlags.prepare(ctx, &cfg)
+	defer cleanup()
+	if err != nil {
+		return err
+	}
+
+	result, err := clangformat.Explain(ctx, cfg, format, option, from, to)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("on top of %s, %s: %s has a %s cost of %s, %s one of %s (%+d).\n", *configFile, option, from,
+		cfg.Metric.Name(), describeTotal(result.FromTotal, cfg), to, describeTotal(result.ToTotal, cfg),
+		result.ToTotal.Weighted-result.FromTotal.Weighted)
+
+	printExplanation(os.Stdout, result, len(cfg.Corpora) > 1, *maxFiles, *maxHunks)
+
+	return nil
+}
+
+// printExplanation summarises the files that differ and shows the largest
+// hunks of the most changed ones.
+func printExplanation(w io.Writer, result *clangformat.Explanation, corpora bool, maxFiles, maxHunks int) {
+	if len(result.Files) == 0 {
+		fmt.Fprintf(w, "both values format the corpus identically.\n")
+		return
+	}
+
+	printFileDiffs(w, result.Files, result.From, result.To, corpora, maxFiles, maxHunks)
+}
+
+// printFileDiffs shows the largest hunks of the most changed of files, which
+// go from the output of from to that of to. Paths carry their corpus when
+// there are several.
+func printFileDiffs(w io.Writer, files []clangformat.FileDiff, from, to string, corpora bool, maxFiles,
+	maxHunks int) {
+	added, removed := 0, 0
+	for _, f := range files {
+		added += f.Stat.Added
+		removed += f.Stat.Removed
+	}
+
+	shown := files[:min(maxFiles, len(files))]
+	fmt.Fprintf(w, "%d files format differently, +%d -%d lines from %s to %s. The %d most changed:\n",
+		len(files), added, removed, from, to, len(shown))
+
+	for _, f := range shown {
+		name := f.Path
+		if corpora {
+			name = path.Join(f.Corpus, f.Path)
+		}
+
+		hunks := f.LargestHunks(maxHunks)
+		shownHunks := fmt.Sprintf("%d hunks", len(hunks))
+		switch {
+		case len(hunks) == 1 && len(f.Hunks) == 1:
+			shownHunks = "1 hunk"
+		case len(hunks) < len(f.Hunks):
+			shownHunks = fmt.Sprintf("the %d largest of %d hunks", len(hunks), len(f.Hunks))
+		}
+
+		fmt.Fprintf(w, "\n%s: +%d -%d, %s\n", name, f.Stat.Added, f.Stat.Removed, shownHunks)
+		fmt.Fprint(w, diff.Unified("a/"+name, "b/"+name, hunks))
+	}
+
+	if rest := len(files) - len(shown); rest > 0 {
+		fmt.Fprintf(w, "\nand %d more files.\n", rest)
+	}
+}
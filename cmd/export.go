@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	clangformat "github.com/javorszky/go-diff-clang/pkg/clang-format"
+)
+
+// export writes the CSV files of a saved run, and its annotated config.
+func export(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+
+	var (
+		resultsFile = fs.String("results", "run.json", "the results of the run to export")
+		dir         = fs.String("dir", ".", "directory to write costs.csv and files.csv to")
+		annotated   = fs.String("annotated", "", "also write the final config to this file, every option"+
+			" commented with the cost of every value tried")
+	)
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s export [flags]\n\n"+
+			"Writes the cost of every value of every option in every pass to costs.csv, and what the final\n"+
+			"config does to every file to files.csv. With -annotated, also writes the final config with the\n"+
+			"costs as comments.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	run, err := clangformat.LoadRun(*resultsFile)
+	if err != nil {
+		return err
+	}
+
+	if *annotated != "" {
+		err = os.WriteFile(*annotated, []byte(run.Annotated()), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeCSV(*dir, run)
+}
+
+// writeCSV writes costs.csv and files.csv of a run to dir.
+func writeCSV(dir string, run *clangformat.Run) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	files := []struct {
+		name  string
+		write func(io.Writer, *clangformat.Run) error
+	}{
+		{name: "costs.csv", write: clangformat.WriteCostsCSV},
+		{name: "files.csv", write: clangformat.WriteFilesCSV},
+	}
+
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		slog.Info("writing CSV", "file", path)
+
+		err := writeFile(path, func(w io.Writer) error { return f.write(w, run) })
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeFile creates file and writes it with write.
+func writeFile(file string, write func(io.Writer) error) error {
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	err = write(out)
+	if err != nil {
+		return err
+	}
+
+	return out.Close()
+}
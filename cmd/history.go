@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	clangformat "github.com/javorszky/go-diff-clang/pkg/clang-format"
+)
+
+// hashLength is how much of a config hash the tables show, enough to pass to
+// -config.
+const hashLength = 12
+
+// history queries the evaluations the search and sensitivity commands
+// appended to the history.
+func history(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+
+	var (
+		file       = fs.String("file", "history.jsonl", "the history to read")
+		best       = fs.Int("best", 10, "list this many of the cheapest configs")
+		option     = fs.String("option", "", "list every evaluation of this option instead")
+		metricName = fs.String("metric", "", "only compare costs of this metric (default the metric of the"+
+			" latest evaluation)")
+		run        = fs.String("run", "", "only look at the evaluations of this run")
+		configHash = fs.String("config", "", "print the config whose hash starts with this instead")
+	)
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s history [flags]\n\n"+
+			"Lists the cheapest configs in the history, every evaluation of an option, or prints a config.\n\n",
+			os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	err := checkCounts(fs, "best")
+	if err != nil {
+		return err
+	}
+
+	records, err := clangformat.LoadHistory(*file)
+	if err != nil {
+		return err
+	}
+
+	if *run != "" {
+		records = slices.DeleteFunc(records, func(r clangformat.HistoryRecord) bool { return r.Run != *run })
+	}
+
+	if len(records) == 0 {
+		return errors.New("there are no evaluations in the history")
+	}
+
+	metric := *metricName
+	if metric == "" {
+		metric = records[len(records)-1].Metric
+	}
+
+	switch {
+	case *configHash != "":
+		r, err := clangformat.FindConfig(records, *configHash)
+		if err != nil {
+			return err
+		}
+
+		fmt.Print(r.Format)
+	case *option != "":
+		found := clangformat.OptionResults(records, *option)
+		if len(found) == 0 {
+			return fmt.Errorf("no evaluation in the history tried %s", *option)
+		}
+
+		printOptionHistory(os.Stdout, found)
+	default:
+		found := clangformat.BestConfigs(records, metric, *best)
+		fmt.Printf("the %d cheapest of the configs evaluated with the %s metric, the file weights and the"+
+			" corpora of its latest evaluation:\n\n", len(found), metric)
+		printBestConfigs(os.Stdout, found)
+	}
+
+	return nil
+}
+
+// printBestConfigs lists configs with the evaluation that found each.
+func printBestConfigs(w io.Writer, records []clangformat.HistoryRecord) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "cost\tconfig\trun\tpass\tfound trying\n")
+
+	for _, r := range records {
+		trying := "-"
+		if r.Option != "" {
+			trying = r.Option + ": " + r.Value
+		}
+
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\n", r.Total.Weighted, shortHash(r.ConfigHash), r.Run, r.Pass,
+			trying)
+	}
+
+	tw.Flush()
+}
+
+// printOptionHistory lists every evaluation of an option as it was made.
+func printOptionHistory(w io.Writer, records []clangformat.HistoryRecord) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "run\tpass\tvalue\tmetric\tcost\tfiles\tduration\tconfig\n")
+
+	for _, r := range records {
+		cost := fmt.Sprint(r.Total.Weighted)
+		if r.Failed() {
+			cost = fmt.Sprintf("rejected (%d)", r.ExitCode)
+		}
+
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%d\t%dms\t%s\n", r.Run, r.Pass, r.Value, r.Metric, cost,
+			len(r.Files), r.DurationMS, shortHash(r.ConfigHash))
+	}
+
+	tw.Flush()
+}
+
+// shortHash cuts a config hash down to hashLength. A record written by hand
+// or by an older version can have a shorter one, or none at all.
+func shortHash(hash string) string {
+	if hash == "" {
+		return "-"
+	}
+
+	return hash[:min(hashLength, len(hash))]
+}
@@ -1,19 +1,412 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 
+	"github.com/javorszky/go-diff-clang/pkg/chart"
 	clangformat "github.com/javorszky/go-diff-clang/pkg/clang-format"
+	"github.com/javorszky/go-diff-clang/pkg/events"
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+	"github.com/javorszky/go-diff-clang/pkg/progress"
+	"github.com/javorszky/go-diff-clang/pkg/report"
 )
 
-func main() {
+// commands are what the first argument can name. Without one, the tool
+// searches for the ideal config.
+var commands = map[string]func(args []string) error{
+	"sensitivity": sensitivity,
+	"history":     history,
+	"rescore":     rescore,
+	"export":      export,
+	"explain":     explain,
+	"compare":     compare,
+}
 
-	format, lc, err := clangformat.IdealClangFormatFile()
+func main() {
+	err := run()
+	if errors.Is(err, context.Canceled) {
+		slog.Error("run interrupted", "error", err)
+		os.Exit(130)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
+}
+
+func run() error {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			return cmd(os.Args[2:])
+		}
+	}
+
+	return search(os.Args[1:])
+}
+
+// search looks for the config with the lowest cost and writes out what it
+// found.
+func search(args []string) error {
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	corpusFlags := addCorpusFlags(fs)
+
+	var objectives stringList
+	var (
+		eventsFile   = fs.String("events", "", "write a JSON lines event stream to this file")
+		showProgress = fs.Bool("progress", true, "show a progress line with an ETA on stdout")
+		printBest    = fs.Bool("print-best-on-interrupt", false, "when interrupted, print the best config"+
+			" found so far")
+		referenceFile = fs.String("reference", "", "a .clang-format file to evaluate before the search and"+
+			" to measure the distance objective from")
+		frontSize = fs.Int("front-size", 8, "with -objective, how many configs of the Pareto front get every"+
+			" option tried on them too; each makes the run up to that much longer")
+		irrelevantFile = fs.String("irrelevant", ".clang-format-doesntmatter", "write the options whose"+
+			" values all cost the same to this file, split by whether they formatted the code identically;"+
+			" empty disables it")
+		reportFile    = fs.String("report", "", "write a single file HTML report of the run to this file")
+		reportSamples = fs.Int("report-samples", 10, "how many of the closest decisions the report shows"+
+			" winner and runner-up diffs for; each takes a clang-format run")
+		resultsFile = fs.String("results", "run.json", "write the final config and every evaluation with its"+
+			" per-file results to this file; empty disables it")
+		idealFile = fs.String("ideal", ".clang-format-ideal", "write the final config to this file; empty"+
+			" disables it")
+		annotate = fs.Bool("annotate", false, "comment every option of the final config with the cost of its"+
+			" value and of every other value tried")
+		charts = fs.Bool("charts", true, "write convergence.svg and option-costs.svg next to the final config")
+		csvDir = fs.String("csv", "", "write costs.csv, the cost of every value tried, and files.csv, what the"+
+			" final config does to every file, to this directory")
+		tieBreak = fs.String("tie-break", string(clangformat.TieBreakFirst), "how to pick among values that cost"+
+			" the same: first, the first in the option catalog, or keep, the value the config has if it's one"+
+			" of them")
+	)
+
+	fs.Var(&objectives, "objective", "another objective to score the candidates on and search a Pareto front"+
+		" of next to -metric; repeatable: a metric like -metric takes, distance, the number of options"+
+		" that differ from -reference, or size[:style], the number of options that differ from the defaults"+
+		" of style (LLVM)")
+	_ = fs.Parse(args)
+
+	if *frontSize < 1 {
+		return fmt.Errorf("-front-size is %d, it needs to be at least 1", *frontSize)
+	}
+
+	cfg, err := corpusFlags.config()
+	if err != nil {
+		return err
+	}
+
+	cfg.FrontSize = *frontSize
+
+	cfg.TieBreak, err = clangformat.ParseTieBreak(*tieBreak)
+	if err != nil {
+		return err
+	}
+
+	if *referenceFile != "" {
+		cfg.Reference, err = clangformat.LoadClangFormat(*referenceFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx := interruptContext()
+
+	cfg.Objectives, err = parseObjectives(ctx, objectives, cfg, *referenceFile, corpusFlags.blame)
+	if err != nil {
+		return err
+	}
+
+	cleanup, err := corpusFlags.prepare(ctx, &cfg)
+	defer cleanup()
+	if err != nil {
+		return err
+	}
+
+	sinks := events.Multi{}
+
+	if *eventsFile != "" {
+		f, err := os.Create(*eventsFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		sink := events.NewJSONLines(f)
+		defer func() {
+			if err := sink.Err(); err != nil {
+				slog.Error("event stream", "error", err)
+			}
+		}()
+
+		sinks = append(sinks, sink)
+	}
+
+	var display *progress.Display
+	if *showProgress {
+		display = progress.New(os.Stdout, clangformat.CandidateCount(), clangformat.Passes)
+		sinks = append(sinks, display)
+	}
+
+	cfg.Events = sinks
+
+	result, err := clangformat.IdealClangFormatFile(ctx, cfg)
+	if display != nil {
+		display.Finish()
+	}
+	if *resultsFile != "" && result != nil {
+		// an interrupted run is saved too, its evaluations are still valid
+		saveErr := result.Save(*resultsFile)
+		if saveErr != nil {
+			slog.Error("could not save results", "file", *resultsFile, "error", saveErr)
+		}
+	}
+	if err != nil && errors.Is(err, context.Canceled) && *printBest {
+		fmt.Printf("interrupted, the best clang format file so far with a %s cost of %s"+
+			" is this:\n\n%s\n", cfg.Metric.Name(), describeTotal(result.Total, cfg), result.Format)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("the ideal clang format file with a %s cost of %s"+
+		" is this:\n\n%s\n", cfg.Metric.Name(), describeTotal(result.Total, cfg), result.Format)
+
+	if *idealFile != "" {
+		ideal := result.Format.String()
+		if *annotate {
+			ideal = result.Annotated()
+		}
+
+		err = os.WriteFile(*idealFile, []byte(ideal), 0644)
+		if err != nil {
+			return err
+		}
+
+		if *charts {
+			err = writeCharts(filepath.Dir(*idealFile), result)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if *irrelevantFile != "" {
+		err = writeIrrelevant(*irrelevantFile, result)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *csvDir != "" {
+		err = writeCSV(*csvDir, result)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *reportFile != "" {
+		err = writeReport(ctx, cfg, result, *reportFile, *reportSamples)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(result.Corpora) > 0 {
+		printCorpora(os.Stdout, result.Corpora)
+	}
+
+	if len(result.Front) > 0 {
+		printFront(os.Stdout, result)
+	}
+
+	return nil
+}
+
+// parseObjectives turns the -objective flags into objectives. distance needs
+// the reference style, size asks clang-format for the defaults of its base
+// style, everything else is a metric spec.
+func parseObjectives(ctx context.Context, specs []string, cfg clangformat.Config, referenceFile string,
+	extra ...metric.Metric) ([]clangformat.Objective, error) {
+	out := make([]clangformat.Objective, 0, len(specs))
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+
+		if spec == "distance" {
+			if cfg.Reference == nil {
+				return nil, errors.New("the distance objective needs -reference")
+			}
+
+			// the options the reference leaves to its base style count too
+			abs, err := filepath.Abs(referenceFile)
+			if err != nil {
+				return nil, err
+			}
+
+			reference, err := clangformat.DumpConfig(ctx, "file:"+abs)
+			if err != nil {
+				return nil, err
+			}
+
+			out = append(out, clangformat.Distance{Reference: reference})
+			continue
+		}
+
+		if name, style, _ := strings.Cut(spec, ":"); name == "size" {
+			if style == "" {
+				style = "LLVM"
+			}
+
+			defaults, err := clangformat.DumpConfig(ctx, style)
+			if err != nil {
+				return nil, err
+			}
+
+			out = append(out, clangformat.ConfigSize{Defaults: defaults})
+			continue
+		}
+
+		m, err := metric.Parse(spec, extra...)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, clangformat.MetricObjective{Metric: m, Weights: cfg.Weights})
+	}
+
+	return out, nil
+}
+
+// maxFrontChanges is how many of the options a point on the front changes
+// compared to the winner are listed.
+const maxFrontChanges = 5
+
+// printFront lists the Pareto front with the options every point sets
+// differently from the winning config. The full configs are in the results
+// file.
+func printFront(w io.Writer, result *clangformat.Run) {
+	fmt.Fprintf(w, "%d of the configs the search evaluated are on the Pareto front of %s:\n\n",
+		len(result.Front), strings.Join(result.Objectives, ", "))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tdiffers from the ideal config in\n", strings.Join(result.Objectives, "\t"))
+
+	for _, p := range result.Front {
+		scores := make([]string, len(p.Scores))
+		for i, s := range p.Scores {
+			scores[i] = strconv.Itoa(s)
+		}
+
+		differs := make([]string, 0)
+		for _, k := range slices.Sorted(maps.Keys(p.Format)) {
+			if result.Format[k] != p.Format[k] {
+				differs = append(differs, fmt.Sprintf("%s: %s", k, p.Format[k]))
+			}
+		}
+
+		summary := "-"
+		switch {
+		case len(differs) > maxFrontChanges:
+			summary = fmt.Sprintf("%s and %d more", strings.Join(differs[:maxFrontChanges], ", "),
+				len(differs)-maxFrontChanges)
+		case len(differs) > 0:
+			summary = strings.Join(differs, ", ")
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\n", strings.Join(scores, "\t"), summary)
+	}
+
+	tw.Flush()
+}
+
+// printCorpora shows what the winning config costs on each corpus, as is and
+// per million lines.
+func printCorpora(w io.Writer, corpora []clangformat.CorpusResult) {
+	fmt.Fprintf(w, "cost per corpus:\n\n")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "corpus\tlines\tweight\tcost\tper million lines\n")
+
+	for _, c := range corpora {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%.0f\n", c.Name, c.Lines,
+			strconv.FormatFloat(c.Weight, 'f', -1, 64), c.Total.Weighted, c.Normalized())
+	}
+
+	tw.Flush()
+	fmt.Fprintln(w)
+}
+
+// writeIrrelevant writes the options that made no difference to the cost in
+// the last pass that checked them.
+func writeIrrelevant(file string, result *clangformat.Run) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = clangformat.WriteIrrelevant(f, result.FinalDecisions())
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// writeCharts draws the charts of a finished run into dir.
+func writeCharts(dir string, result *clangformat.Run) error {
+	charts := []struct {
+		name string
+		draw func(io.Writer, *clangformat.Run) error
+	}{
+		{name: "convergence.svg", draw: chart.Convergence},
+		{name: "option-costs.svg", draw: chart.OptionCosts},
+	}
+
+	for _, c := range charts {
+		file := filepath.Join(dir, c.name)
+		slog.Info("writing chart", "file", file)
+
+		err := writeFile(file, func(w io.Writer) error { return c.draw(w, result) })
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeReport renders the HTML report of a finished run, formatting the corpus
+// a few more times for its samples.
+func writeReport(ctx context.Context, cfg clangformat.Config, result *clangformat.Run, file string, samples int) error {
+	slog.Info("writing report", "file", file, "samples", samples)
+
+	found, err := report.Samples(ctx, cfg, result, samples)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = report.Write(f, result, found)
+	if err != nil {
+		return err
+	}
 
-	fmt.Printf("the ideal clang format file changing %d lines"+
-		" is this:\n\n%s\n", lc, format)
+	return f.Close()
 }
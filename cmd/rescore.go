@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/javorszky/go-diff-clang/pkg/blame"
+	clangformat "github.com/javorszky/go-diff-clang/pkg/clang-format"
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+)
+
+// rescore replays the decisions of a recorded run with another metric or
+// tie-break and shows which winners change.
+func rescore(args []string) error {
+	fs := flag.NewFlagSet("rescore", flag.ExitOnError)
+
+	var weights stringList
+	var (
+		resultsFile = fs.String("results", "run.json", "the results of the run to replay")
+		metricSpec  = fs.String("metric", "", "metric to score the recorded diffs with, like the search takes"+
+			" except for blame; empty keeps the recorded per-file costs")
+		tieBreak = fs.String("tie-break", string(clangformat.TieBreakFirst), "how to pick among values that"+
+			" cost the same: first or keep")
+		outputFile = fs.String("output", "", "also write the replayed decisions to this file as JSON")
+	)
+
+	fs.Var(&weights, "weight", "glob=weight rule to reweigh the per-file costs with, with or without -metric,"+
+		" repeatable (default the weights the run recorded)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s rescore [flags]\n\n"+
+			"Replays the decisions of a run with another metric or tie-break, using the diffs the run recorded\n"+
+			"with -record-diffs, and shows which winners would have changed.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	run, err := clangformat.LoadRun(*resultsFile)
+	if err != nil {
+		return err
+	}
+
+	cfg := clangformat.RescoreConfig{}
+
+	cfg.TieBreak, err = clangformat.ParseTieBreak(*tieBreak)
+	if err != nil {
+		return err
+	}
+
+	if *metricSpec != "" {
+		// blame is known so it gets a clear error instead of an unknown one
+		blameMetric := &blame.Metric{}
+
+		cfg.Metric, err = metric.Parse(*metricSpec, blameMetric)
+		if err != nil {
+			return err
+		}
+
+		if metric.Uses(cfg.Metric, blameMetric.Name()) {
+			return errors.New("the blame metric can't rescore a run: it needs the history of the corpus," +
+				" which the recorded diffs don't have")
+		}
+	}
+
+	for _, w := range weights {
+		rule, err := metric.ParseWeightRule(w)
+		if err != nil {
+			return err
+		}
+
+		cfg.Weights = append(cfg.Weights, rule)
+	}
+
+	result, err := clangformat.Rescore(run, cfg)
+	if err != nil {
+		return err
+	}
+
+	recordedTieBreak := run.TieBreak
+	if recordedTieBreak == "" {
+		recordedTieBreak = "unrecorded"
+	}
+
+	fmt.Printf("replayed %d of the %d decisions of %s, made with %s and tie-break %s, with %s and tie-break"+
+		" %s.\n\n", len(result.Decisions), len(run.Decisions), *resultsFile, run.Metric, recordedTieBreak,
+		result.Metric, result.TieBreak)
+	printRescore(os.Stdout, result)
+
+	if *outputFile != "" {
+		raw, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(*outputFile, append(raw, '\n'), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printRescore lists the decisions whose winner changed, and where the replay
+// needs fresh evaluations to go on.
+func printRescore(w io.Writer, result *clangformat.RescoreResult) {
+	changed := slices.DeleteFunc(slices.Clone(result.Decisions), func(d clangformat.RescoredDecision) bool {
+		return !d.Changed()
+	})
+
+	if len(changed) == 0 {
+		fmt.Fprintf(w, "no winner changes.\n")
+	} else {
+		fmt.Fprintf(w, "%d of the winners change:\n\n", len(changed))
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "pass\toption\trecorded\tcost\treplayed\tcost\n")
+		for _, d := range changed {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%d\n", d.Pass, d.Option, d.Recorded, d.Costs[d.Recorded],
+				d.Value, d.Costs[d.Value])
+		}
+		tw.Flush()
+	}
+
+	if len(result.Pending) == 0 {
+		fmt.Fprintf(w, "\nthe replay followed the run to the end, this is its config:\n\n%s\n", result.Format)
+		return
+	}
+
+	// what the run had for an option is what it last decided on it
+	recorded := make(map[string]string)
+	for _, d := range result.Decisions {
+		recorded[d.Option] = d.Recorded
+	}
+
+	differs := make([]string, 0, len(result.Differs))
+	for _, k := range slices.Sorted(maps.Keys(result.Differs)) {
+		differs = append(differs, fmt.Sprintf("%s: %s where the run had %s", k, result.Differs[k], recorded[k]))
+	}
+
+	first := result.Pending[0]
+	fmt.Fprintf(w, "\nthe replay leaves the recorded path at pass %d, %s: the replay's config has\n  %s\n"+
+		"so it and the %d decisions after it need fresh evaluations. Run the search with the new"+
+		" settings to get them.\n", first.Pass, first.Option, strings.Join(differs, "\n  "), len(result.Pending)-1)
+}
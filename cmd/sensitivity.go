@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	clangformat "github.com/javorszky/go-diff-clang/pkg/clang-format"
+)
+
+// sensitivity evaluates every alternative value of every option of a config
+// and prints the options ranked by how much deviating from them costs.
+func sensitivity(args []string) error {
+	fs := flag.NewFlagSet("sensitivity", flag.ExitOnError)
+	corpusFlags := addCorpusFlags(fs)
+
+	var (
+		configFile = fs.String("config", ".clang-format-ideal", "the .clang-format file to analyse")
+		outputFile = fs.String("output", "", "also write the costs of every value to this file as JSON")
+	)
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s sensitivity [flags]\n\n"+
+			"For every option of -config, formats the corpus with each of its other values, everything else\n"+
+			"fixed, and ranks the options by how much more the cheapest alternative costs.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	cfg, err := corpusFlags.config()
+	if err != nil {
+		return err
+	}
+
+	format, err := clangformat.LoadClangFormat(*configFile)
+	if err != nil {
+		return err
+	}
+
+	ctx := interruptContext()
+
+	cleanup, err := corpusFlags.prepare(ctx, &cfg)
+	defer cleanup()
+	if err != nil {
+		return err
+	}
+
+	result, err := clangformat.Sensitivity(ctx, cfg, format)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s has a %s cost of %s. Deviating from each option costs:\n\n", *configFile, result.Metric,
+		describeTotal(result.Total, cfg))
+	printSensitivity(os.Stdout, result)
+
+	if *outputFile != "" {
+		raw, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(*outputFile, append(raw, '\n'), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printSensitivity lists the options in the order of their impact, with the
+// cheapest and the most expensive alternative of each.
+func printSensitivity(w io.Writer, result *clangformat.SensitivityResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "option\tvalue\tcheapest alternative\tworst alternative\tnote\n")
+
+	for _, o := range result.Options {
+		cheapest, worst := "-", "-"
+		if len(o.Costs) > 1 {
+			cheapest = describeAlternative(o, o.Cheapest)
+			worst = describeAlternative(o, o.Worst)
+		}
+
+		note := ""
+		switch {
+		case o.Identical:
+			note = "identical output"
+		case o.Cheapest < 0:
+			note = "an alternative is cheaper"
+		case o.Free():
+			note = "taste"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", o.Option, o.Value, cheapest, worst, note)
+	}
+
+	tw.Flush()
+}
+
+// describeAlternative names the values whose cost differs from the chosen
+// one's by delta, and the difference.
+func describeAlternative(o clangformat.OptionSensitivity, delta int) string {
+	values := make([]string, 0)
+	for _, value := range slices.Sorted(maps.Keys(o.Costs)) {
+		if value != o.Value && o.Costs[value]-o.Costs[o.Value] == delta {
+			values = append(values, value)
+		}
+	}
+
+	if len(values) > 2 {
+		values = append(values[:2], fmt.Sprintf("%d more", len(values)-2))
+	}
+
+	return fmt.Sprintf("%s (%+d)", strings.Join(values, ", "), delta)
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/javorszky/go-diff-clang/pkg/blame"
+	clangformat "github.com/javorszky/go-diff-clang/pkg/clang-format"
+	"github.com/javorszky/go-diff-clang/pkg/corpus"
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+)
+
+// stringList is a flag that can be repeated, or given a comma separated list.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, strings.Split(v, ",")...)
+	return nil
+}
+
+// logLevels maps the values of the -log-level flag onto slog levels.
+var logLevels = map[string]slog.Level{
+	"quiet":   slog.LevelWarn,
+	"normal":  slog.LevelInfo,
+	"verbose": slog.LevelDebug,
+}
+
+// corpusFlags are the flags of every command that formats the corpus: which
+// corpora and files, how the changes are scored, and how much to log.
+type corpusFlags struct {
+	include stringList
+	exclude stringList
+	corpora stringList
+	ext     stringList
+	weights stringList
+	authors stringList
+
+	discover      *bool
+	logLevel      *string
+	clone         *bool
+	metricSpec    *string
+	expectRemote  *string
+	blameHalfLife *time.Duration
+	blameCache    *string
+	history       *string
+	recordDiffs   *bool
+
+	// level and blame are set by config.
+	level slog.Level
+	blame *blame.Metric
+}
+
+func addCorpusFlags(fs *flag.FlagSet) *corpusFlags {
+	f := &corpusFlags{
+		discover: fs.Bool("discover", true, "discover source files in the corpus and write "+
+			clangformat.FilesList+"; set to false to use an existing one"),
+		logLevel: fs.String("log-level", "normal", "how much to log: quiet, normal or verbose"),
+		clone:    fs.Bool("clone", false, "work on a disposable clone of the corpus instead of the corpus itself"),
+		metricSpec: fs.String("metric", "lines", "metric to minimise: one of "+
+			strings.Join(metric.Names(), ", ")+", blame, or a weighted sum like lines+10*hunks"),
+		expectRemote: fs.String("expect-remote", "nginx/unit", "refuse to run unless the corpus' origin"+
+			" remote contains this; empty disables the check"),
+		blameHalfLife: fs.Duration("blame-half-life", 0, "for the blame metric, halve the weight of a line"+
+			" for every this much age, e.g. 8760h; 0 weighs every line the same"),
+		blameCache: fs.String("blame-cache", ".blame-cache", "directory to cache blame results in per corpus"+
+			" commit; empty keeps them in memory only"),
+		history: fs.String("history", "history.jsonl", "append every evaluation with its config to this JSON"+
+			" lines file; empty disables it"),
+		recordDiffs: fs.Bool("record-diffs", false, "keep the diff of every file of every evaluation in the"+
+			" results and history, for rescore to try other metrics on"),
+	}
+
+	fs.Var(&f.include, "include", "glob relative to the corpus of files to include, repeatable (default src/**)")
+	fs.Var(&f.exclude, "exclude", "glob relative to the corpus of files to exclude, repeatable")
+	fs.Var(&f.ext, "ext", "extension (.c) or extension set (c, cpp, objc) to include, repeatable (default c)")
+	fs.Var(&f.authors, "blame-author", "author=weight for the blame metric, author being a name or email,"+
+		" repeatable")
+	fs.Var(&f.corpora, "corpus", "dir or dir=weight of a git repository to score candidates on, repeatable;"+
+		" several corpora are compared per million lines (default "+clangformat.UnitDirectory+")")
+	fs.Var(&f.weights, "weight", "glob=weight rule multiplying the cost of matching files, relative to the corpus,"+
+		" repeatable; the last matching rule wins, 0 keeps files for observation only")
+
+	return f
+}
+
+// config sets up logging and builds the config the flags describe. It does
+// not touch the corpora yet, that's prepare.
+func (f *corpusFlags) config() (clangformat.Config, error) {
+	level, ok := logLevels[*f.logLevel]
+	if !ok {
+		return clangformat.Config{}, fmt.Errorf("unknown log level %q, want quiet, normal or verbose", *f.logLevel)
+	}
+
+	f.level = level
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
+	f.blame = &blame.Metric{
+		HalfLife: *f.blameHalfLife,
+		Authors:  make(map[string]float64),
+	}
+	for _, a := range f.authors {
+		author, weight, err := blame.ParseAuthorWeight(a)
+		if err != nil {
+			return clangformat.Config{}, err
+		}
+
+		f.blame.Authors[author] = weight
+	}
+
+	costMetric, err := metric.Parse(*f.metricSpec, f.blame)
+	if err != nil {
+		return clangformat.Config{}, err
+	}
+
+	if *f.clone && !*f.discover {
+		return clangformat.Config{}, errors.New("-clone needs -discover: a hand made " + clangformat.FilesList +
+			" points at the original corpus, not the clone")
+	}
+
+	cfg := clangformat.Config{
+		Corpora:     []clangformat.Corpus{clangformat.DefaultCorpus()},
+		Metric:      costMetric,
+		RecordDiffs: *f.recordDiffs,
+	}
+
+	if len(f.corpora) > 0 {
+		cfg.Corpora, err = parseCorpora(f.corpora)
+		if err != nil {
+			return clangformat.Config{}, err
+		}
+	}
+
+	for _, w := range f.weights {
+		rule, err := metric.ParseWeightRule(w)
+		if err != nil {
+			return clangformat.Config{}, err
+		}
+
+		cfg.Weights = append(cfg.Weights, rule)
+	}
+
+	return cfg, nil
+}
+
+// prepare gets the corpora of cfg ready to be formatted: it checks they are
+// safe to reset, clones them if asked to, discovers their files, loads blame
+// if a metric needs it and opens the history. The returned func removes the
+// clones and closes the history, it is to be called once the corpora are no
+// longer needed, even on error.
+func (f *corpusFlags) prepare(ctx context.Context, cfg *clangformat.Config) (func(), error) {
+	clones := make([]string, 0)
+	var history *os.File
+	cleanup := func() {
+		for _, dir := range clones {
+			if err := os.RemoveAll(dir); err != nil {
+				slog.Warn("could not remove the clone", "dir", dir, "error", err)
+			}
+		}
+
+		if history != nil {
+			if err := cfg.History.Err(); err != nil {
+				slog.Error("history", "file", history.Name(), "error", err)
+			}
+			if err := history.Close(); err != nil {
+				slog.Error("could not close the history", "file", history.Name(), "error", err)
+			}
+		}
+	}
+
+	if len(cfg.Corpora) > 1 && usesMetric(*cfg, f.blame.Name()) {
+		return cleanup, errors.New("the blame metric works on a single corpus")
+	}
+
+	for i := range cfg.Corpora {
+		c := &cfg.Corpora[i]
+
+		// the origin check is about the default corpus, others are
+		// whatever the user pointed at
+		remote := *f.expectRemote
+		if len(f.corpora) > 0 {
+			remote = ""
+		}
+
+		err := corpus.CheckSafe(ctx, c.Dir, remote)
+		var dirty *corpus.DirtyError
+		switch {
+		case errors.As(err, &dirty) && *f.clone:
+			slog.Warn("the corpus has uncommitted changes, they will not be part of the clone",
+				"corpus", c.Name, "changes", len(dirty.Changes))
+		case err != nil:
+			return cleanup, err
+		}
+
+		if *f.clone {
+			dir, err := corpus.Clone(ctx, c.Dir, clangformat.TargetDirectory)
+			if err != nil {
+				return cleanup, err
+			}
+			clones = append(clones, dir)
+
+			slog.Info("working on a clone of the corpus", "corpus", c.Name, "dir", dir)
+			c.Dir = dir
+		}
+	}
+
+	if *f.discover {
+		include, ext := f.include, f.ext
+		if len(include) == 0 {
+			include = stringList{"src/**"}
+		}
+		if len(ext) == 0 {
+			ext = stringList{"c"}
+		}
+
+		for _, c := range cfg.Corpora {
+			found, err := corpus.Discover(corpus.DiscoverConfig{
+				Root:       c.Dir,
+				Include:    include,
+				Exclude:    f.exclude,
+				Extensions: ext,
+			})
+			if err != nil {
+				return cleanup, err
+			}
+
+			if f.level <= slog.LevelInfo {
+				fmt.Fprint(os.Stderr, found.Summary())
+			}
+
+			err = found.WriteList(c.FilesList)
+			if err != nil {
+				return cleanup, err
+			}
+		}
+	}
+
+	// a hand made list can name files discovery would have left out
+	for _, c := range cfg.Corpora {
+		files, err := readFilesList(c.FilesList, c.Dir)
+		if err != nil {
+			return cleanup, err
+		}
+
+		err = corpus.CheckTracked(ctx, c.Dir, files)
+		if err != nil {
+			return cleanup, err
+		}
+	}
+
+	if usesMetric(*cfg, f.blame.Name()) {
+		c := cfg.Corpora[0]
+
+		var err error
+		f.blame.Cache, err = blame.NewCache(ctx, c.Dir, *f.blameCache)
+		if err != nil {
+			return cleanup, err
+		}
+
+		files, err := readFilesList(c.FilesList, c.Dir)
+		if err != nil {
+			return cleanup, err
+		}
+
+		slog.Info("loading blame", "files", len(files), "commit", f.blame.Cache.Commit)
+
+		err = f.blame.Cache.Load(ctx, files)
+		if err != nil {
+			return cleanup, err
+		}
+	}
+
+	if *f.history != "" {
+		var err error
+		history, err = os.OpenFile(*f.history, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return cleanup, err
+		}
+
+		// runs started within the same second still need telling apart
+		cfg.History = clangformat.NewHistory(history, time.Now().UTC().Format(time.RFC3339Nano), *cfg)
+	}
+
+	return cleanup, nil
+}
+
+// interruptContext is cancelled on the first interrupt or SIGTERM, leaving
+// the command to restore the corpus. A second signal kills the process.
+func interruptContext() context.Context {
+	// stop is only called once a signal arrived, the process exiting takes
+	// care of it otherwise.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-ctx.Done()
+		// Cleanup is underway, a second signal gets the default behaviour
+		// and kills the process.
+		stop()
+		slog.Warn("interrupted, restoring the corpus; interrupt again to quit immediately")
+	}()
+
+	return ctx
+}
+
+// checkCounts makes sure none of the int flags called names is negative,
+// they are how many of something to show.
+func checkCounts(fs *flag.FlagSet, names ...string) error {
+	for _, name := range names {
+		n := fs.Lookup(name).Value.(flag.Getter).Get().(int)
+		if n < 0 {
+			return fmt.Errorf("-%s is negative: %d", name, n)
+		}
+	}
+
+	return nil
+}
+
+// readFilesList returns the files in the list clang-format is given, relative
+// to the corpus root.
+func readFilesList(list, corpusDir string) ([]string, error) {
+	raw, err := os.ReadFile(list)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0)
+	for _, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		rel, err := filepath.Rel(corpusDir, line)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, filepath.ToSlash(rel))
+	}
+
+	return files, nil
+}
+
+// usesMetric reports whether the cost metric or any objective uses the
+// metric called name.
+func usesMetric(cfg clangformat.Config, name string) bool {
+	if metric.Uses(cfg.Metric, name) {
+		return true
+	}
+
+	for _, o := range cfg.Objectives {
+		if m, ok := o.(clangformat.MetricObjective); ok && metric.Uses(m.Metric, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseCorpora parses the -corpus flags, making sure every corpus has a name
+// of its own as results and files lists are told apart by it.
+func parseCorpora(specs []string) ([]clangformat.Corpus, error) {
+	out := make([]clangformat.Corpus, 0, len(specs))
+	seen := make(map[string]bool)
+
+	for _, spec := range specs {
+		c, err := clangformat.ParseCorpus(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		if seen[c.Name] {
+			return nil, fmt.Errorf("two corpora are called %q", c.Name)
+		}
+		seen[c.Name] = true
+
+		out = append(out, c)
+	}
+
+	return out, nil
+}
+
+// describeTotal prints the weighted cost, and the raw one as well when
+// weights were in play.
+func describeTotal(total metric.Total, cfg clangformat.Config) string {
+	unit := ""
+	if len(cfg.Corpora) > 1 {
+		unit = " per million lines across corpora"
+	}
+
+	if len(cfg.Weights) == 0 {
+		return fmt.Sprintf("%d%s", total.Weighted, unit)
+	}
+
+	return fmt.Sprintf("%d%s (%d before file weights)", total.Weighted, unit, total.Raw)
+}
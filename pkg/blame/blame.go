@@ -0,0 +1,262 @@
+package blame
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Line is who last touched a line, according to git blame.
+type Line struct {
+	Commit     string `json:"commit"`
+	Author     string `json:"author"`
+	AuthorMail string `json:"author_mail"`
+	AuthorTime int64  `json:"author_time"`
+}
+
+// Cache holds the blame of corpus files at one commit. Every file is blamed
+// at most once per commit: results are kept in memory and, if Dir is set, on
+// disk under Dir/<commit>/ so later runs on the same commit skip git blame.
+type Cache struct {
+	mu sync.Mutex
+
+	corpusDir string
+	dir       string
+
+	// Commit is the corpus commit that was blamed.
+	Commit string
+	// CommitTime is when Commit was committed, ages are measured from it.
+	CommitTime time.Time
+
+	files map[string][]Line
+}
+
+// NewCache returns a cache for the HEAD commit of the corpus. cacheDir may be
+// empty to keep the cache in memory only.
+func NewCache(ctx context.Context, corpusDir, cacheDir string) (*Cache, error) {
+	out, err := git(ctx, corpusDir, "log", "-1", "--format=%H %ct", "HEAD")
+	if err != nil {
+		return nil, errors.Wrap(err, "reading HEAD")
+	}
+
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return nil, errors.Errorf("unexpected git log output %q", out)
+	}
+
+	ts, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "commit time %q", fields[1])
+	}
+
+	return &Cache{
+		corpusDir:  corpusDir,
+		dir:        cacheDir,
+		Commit:     fields[0],
+		CommitTime: time.Unix(ts, 0),
+		files:      make(map[string][]Line),
+	}, nil
+}
+
+// Load blames every file that is not cached yet. Paths are relative to the
+// corpus root.
+func (c *Cache) Load(ctx context.Context, files []string) error {
+	for _, f := range files {
+		_, err := c.get(ctx, f)
+		if err != nil {
+			return errors.Wrapf(err, "blaming %s", f)
+		}
+	}
+
+	return nil
+}
+
+// Lines returns the blame of every line of file, the first line at index 0.
+// The second return value is false if the file was never loaded.
+func (c *Cache) Lines(file string) ([]Line, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lines, ok := c.files[file]
+
+	return lines, ok
+}
+
+func (c *Cache) get(ctx context.Context, file string) ([]Line, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if lines, ok := c.files[file]; ok {
+		return lines, nil
+	}
+
+	lines, err := c.readDisk(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if lines == nil {
+		out, err := git(ctx, c.corpusDir, "blame", "--porcelain", c.Commit, "--", file)
+		if err != nil {
+			return nil, err
+		}
+
+		lines, err = parsePorcelain(out)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsePorcelain")
+		}
+
+		err = c.writeDisk(file, lines)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.files[file] = lines
+
+	return lines, nil
+}
+
+// diskPath hashes the file name so nested paths don't need directories.
+func (c *Cache) diskPath(file string) string {
+	sum := sha256.Sum256([]byte(file))
+
+	return filepath.Join(c.dir, c.Commit, hex.EncodeToString(sum[:8])+".json")
+}
+
+func (c *Cache) readDisk(file string) ([]Line, error) {
+	if c.dir == "" {
+		return nil, nil
+	}
+
+	raw, err := os.ReadFile(c.diskPath(file))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading blame cache")
+	}
+
+	lines := make([]Line, 0)
+	err = json.Unmarshal(raw, &lines)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decoding blame cache for %s", file)
+	}
+
+	return lines, nil
+}
+
+func (c *Cache) writeDisk(file string, lines []Line) error {
+	if c.dir == "" {
+		return nil
+	}
+
+	p := c.diskPath(file)
+
+	err := os.MkdirAll(filepath.Dir(p), 0755)
+	if err != nil {
+		return errors.Wrap(err, "creating blame cache")
+	}
+
+	raw, err := json.Marshal(lines)
+	if err != nil {
+		return errors.Wrap(err, "encoding blame cache")
+	}
+
+	err = os.WriteFile(p, raw, 0644)
+	if err != nil {
+		return errors.Wrap(err, "writing blame cache")
+	}
+
+	return nil
+}
+
+// parsePorcelain reads git blame --porcelain output. Every line starts with a
+// header naming its commit; the commit's details only follow the first time
+// it appears.
+func parsePorcelain(out []byte) ([]Line, error) {
+	commits := make(map[string]*Line)
+	lines := make([]Line, 0)
+
+	var current *Line
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		text := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(text, "\t"):
+			// the content of the line ends its entry
+			if current == nil {
+				return nil, errors.New("line content without a header")
+			}
+			lines = append(lines, *current)
+			current = nil
+		case current == nil:
+			fields := strings.Fields(text)
+			if len(fields) < 3 {
+				return nil, errors.Errorf("malformed header %q", text)
+			}
+
+			info, ok := commits[fields[0]]
+			if !ok {
+				info = &Line{Commit: fields[0]}
+				commits[fields[0]] = info
+			}
+			current = info
+		default:
+			key, value, _ := strings.Cut(text, " ")
+			switch key {
+			case "author":
+				current.Author = value
+			case "author-mail":
+				current.AuthorMail = strings.Trim(value, "<>")
+			case "author-time":
+				ts, err := strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					return nil, errors.Wrapf(err, "author-time %q", value)
+				}
+				current.AuthorTime = ts
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "scanning")
+	}
+
+	return lines, nil
+}
+
+func git(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	gitCtx, gitCxl := context.WithTimeout(ctx, time.Minute)
+	defer gitCxl()
+
+	var stdOut, stdErr bytes.Buffer
+
+	cmd := exec.CommandContext(gitCtx, "git", append([]string{"--no-pager"}, args...)...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdOut
+	cmd.Stderr = &stdErr
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, errors.Wrapf(err, "git %s: %s", args[0], strings.TrimSpace(stdErr.String()))
+	}
+
+	return stdOut.Bytes(), nil
+}
@@ -0,0 +1,118 @@
+package blame
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+)
+
+func TestMetric(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	commit := func(author, date, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "a.c"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{{"add", "a.c"}, {"commit", "--quiet", "-m", "change"}} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = dir
+			cmd.Env = append(os.Environ(),
+				"GIT_AUTHOR_NAME="+author, "GIT_AUTHOR_EMAIL="+author+"@example.com", "GIT_AUTHOR_DATE="+date,
+				"GIT_COMMITTER_NAME="+author, "GIT_COMMITTER_EMAIL="+author+"@example.com",
+				"GIT_COMMITTER_DATE="+date,
+			)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("git %v: %v\n%s", args, err, out)
+			}
+		}
+	}
+
+	if out, err := exec.Command("git", "init", "--quiet", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	commit("old", "2020-01-01T00:00:00Z", "int a;\nint b;\n")
+	commit("new", "2021-01-01T00:00:00Z", "int a;\nint b;\nint c;\n")
+
+	cacheDir := t.TempDir()
+	cache, err := NewCache(ctx, dir, cacheDir)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	if err = cache.Load(ctx, []string{"a.c"}); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	lines, ok := cache.Lines("a.c")
+	if !ok || len(lines) != 3 || lines[0].Author != "old" || lines[2].AuthorMail != "new@example.com" {
+		t.Fatalf("Lines() = %+v, %v", lines, ok)
+	}
+
+	// A second cache on the same commit is served from disk.
+	cached, err := NewCache(ctx, dir, cacheDir)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	if got, err := cached.readDisk("a.c"); err != nil || len(got) != 3 {
+		t.Fatalf("readDisk() = %+v, %v", got, err)
+	}
+
+	// every line reformatted
+	f := metric.NewFile("a.c", []byte("int a;\nint b;\nint c;\n"), []byte("int  a;\nint  b;\nint  c;\n"))
+
+	tests := []struct {
+		name string
+		m    *Metric
+		want float64
+	}{
+		{name: "plain", m: &Metric{Cache: cache}, want: 3},
+		{name: "half life", m: &Metric{Cache: cache, HalfLife: 366 * 24 * time.Hour}, want: 0.5 + 0.5 + 1},
+		{
+			name: "author by name and by email",
+			m:    &Metric{Cache: cache, Authors: map[string]float64{"old": 0, "new@example.com": 3}},
+			want: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.FileCost(f); got != tt.want {
+				t.Errorf("FileCost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAuthorWeight(t *testing.T) {
+	tests := []struct {
+		in         string
+		wantAuthor string
+		wantWeight float64
+		wantErr    bool
+	}{
+		{in: "bot@example.com=0", wantAuthor: "bot@example.com", wantWeight: 0},
+		{in: "Jane Doe = 2.5", wantAuthor: "Jane Doe", wantWeight: 2.5},
+		{in: "Jane Doe", wantErr: true},
+		{in: "bot@example.com=-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			author, weight, err := ParseAuthorWeight(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAuthorWeight() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if author != tt.wantAuthor || weight != tt.wantWeight {
+				t.Errorf("ParseAuthorWeight() = %q, %v, want %q, %v", author, weight, tt.wantAuthor, tt.wantWeight)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package blame
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/javorszky/go-diff-clang/pkg/diff"
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+	"github.com/pkg/errors"
+)
+
+// Metric counts the lines whose blame a candidate would rewrite. Each line
+// can be weighted by how recently it was written and by who wrote it, so that
+// disturbing fresh or actively maintained code costs more.
+type Metric struct {
+	Cache *Cache
+
+	// HalfLife, when not zero, halves the weight of a line for every
+	// HalfLife of age it has, measured from the corpus commit.
+	HalfLife time.Duration
+
+	// Authors maps author names or emails to weights. Lines by authors not
+	// listed weigh 1.
+	Authors map[string]float64
+}
+
+func (m *Metric) Name() string { return "blame" }
+
+func (m *Metric) FileCost(f *metric.File) float64 {
+	blamed, ok := m.Cache.Lines(f.Path)
+
+	total := 0.0
+	for _, l := range f.Lines {
+		if l.Op != diff.Delete {
+			continue
+		}
+
+		// Without blame for the line, count it plainly.
+		if !ok || l.OldLine > len(blamed) {
+			total++
+			continue
+		}
+
+		total += m.lineWeight(blamed[l.OldLine-1])
+	}
+
+	return total
+}
+
+func (m *Metric) lineWeight(l Line) float64 {
+	weight := 1.0
+
+	if m.HalfLife > 0 {
+		age := m.Cache.CommitTime.Sub(time.Unix(l.AuthorTime, 0))
+		if age < 0 {
+			age = 0
+		}
+
+		weight *= math.Pow(0.5, float64(age)/float64(m.HalfLife))
+	}
+
+	if w, ok := m.Authors[l.AuthorMail]; ok {
+		weight *= w
+	} else if w, ok := m.Authors[l.Author]; ok {
+		weight *= w
+	}
+
+	return weight
+}
+
+// ParseAuthorWeight parses an author=weight pair, where author is a name or an
+// email address. Like file weights, the weight can't be negative.
+func ParseAuthorWeight(s string) (string, float64, error) {
+	author, weight, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", 0, errors.Errorf("author weight %q is not author=weight", s)
+	}
+
+	w, err := strconv.ParseFloat(strings.TrimSpace(weight), 64)
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "weight of author %q", s)
+	}
+
+	if w < 0 {
+		return "", 0, errors.Errorf("weight of author %q is negative", s)
+	}
+
+	return strings.TrimSpace(author), w, nil
+}
@@ -0,0 +1,114 @@
+package chart
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"slices"
+	"testing"
+
+	clangformat "github.com/javorszky/go-diff-clang/pkg/clang-format"
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+)
+
+// elements parses the SVG and counts its elements by name.
+func elements(t *testing.T, raw []byte) map[string]int {
+	t.Helper()
+
+	counts := make(map[string]int)
+	dec := xml.NewDecoder(bytes.NewReader(raw))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return counts
+		}
+		if err != nil {
+			t.Fatalf("the chart is not well formed: %v\n%s", err, raw)
+		}
+
+		if start, ok := tok.(xml.StartElement); ok {
+			counts[start.Name.Local]++
+		}
+	}
+}
+
+func TestConvergence(t *testing.T) {
+	evaluation := func(value string, cost, exitCode int) clangformat.Evaluation {
+		return clangformat.Evaluation{Pass: 1, Option: "AlignArrayOfStructures", Value: value,
+			Total: metric.Total{Raw: cost, Weighted: cost}, ExitCode: exitCode}
+	}
+
+	run := &clangformat.Run{
+		Metric: "lines",
+		Evaluations: []clangformat.Evaluation{
+			evaluation("None", 120, 0),
+			evaluation("Left", 0, 1),
+			evaluation("Right", 100, 0),
+			evaluation("Center", 5000, 0),
+			evaluation("None", 90, 0),
+		},
+	}
+
+	buf := bytes.Buffer{}
+	err := Convergence(&buf, run)
+	if err != nil {
+		t.Fatalf("Convergence() error = %v", err)
+	}
+
+	got := elements(t, buf.Bytes())
+	// the rejected evaluation has no cost, Center costs more than the first
+	// evaluation and is left out
+	if got["circle"] != 3 || got["polyline"] != 1 || got["svg"] != 1 {
+		t.Errorf("Convergence() drew %v, want 3 circles and a line", got)
+	}
+
+	if !bytes.Contains(buf.Bytes(), []byte("not shown: 1 evaluation costing more than")) {
+		t.Errorf("Convergence() does not say an evaluation was left out")
+	}
+}
+
+func TestOptionCosts(t *testing.T) {
+	run := &clangformat.Run{
+		Metric: "lines",
+		Decisions: []clangformat.Decision{
+			{Pass: 1, Option: "AlignArrayOfStructures", Value: "Right", Costs: map[string]int{"None": 120, "Right": 100}},
+			{Pass: 1, Option: "UseTab", Value: "Never", Costs: map[string]int{"Never": 100, "Always": 5000}},
+			{Pass: 2, Option: "AlignArrayOfStructures", Value: "None", Costs: map[string]int{"None": 90, "Right": 95}},
+		},
+	}
+
+	buf := bytes.Buffer{}
+	err := OptionCosts(&buf, run)
+	if err != nil {
+		t.Fatalf("OptionCosts() error = %v", err)
+	}
+
+	got := elements(t, buf.Bytes())
+	if got["rect"] != 4 {
+		t.Errorf("OptionCosts() drew %d bars, want 2 for each of the 2 options", got["rect"])
+	}
+
+	// UseTab swings the most and comes first
+	useTab := bytes.Index(buf.Bytes(), []byte(">UseTab<"))
+	align := bytes.Index(buf.Bytes(), []byte(">AlignArrayOfStructures<"))
+	if useTab < 0 || align < 0 || useTab > align {
+		t.Errorf("OptionCosts() did not put UseTab before AlignArrayOfStructures")
+	}
+}
+
+func TestTicks(t *testing.T) {
+	tests := []struct {
+		lo, hi float64
+		n      int
+		want   []float64
+	}{
+		{lo: 0, hi: 10, n: 5, want: []float64{0, 2, 4, 6, 8, 10}},
+		{lo: 17666, hi: 18102, n: 4, want: []float64{17600, 17800, 18000, 18200}},
+		{lo: 3, hi: 3, n: 5, want: []float64{3, 4}},
+	}
+	for _, tt := range tests {
+		if got := ticks(tt.lo, tt.hi, tt.n); !slices.Equal(got, tt.want) {
+			t.Errorf("ticks(%v, %v, %d) = %v, want %v", tt.lo, tt.hi, tt.n, got, tt.want)
+		}
+	}
+}
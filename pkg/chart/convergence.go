@@ -0,0 +1,137 @@
+package chart
+
+import (
+	"fmt"
+	"io"
+
+	clangformat "github.com/javorszky/go-diff-clang/pkg/clang-format"
+)
+
+const (
+	convergenceWidth  = 900
+	convergenceHeight = 420
+
+	marginLeft   = 70
+	marginRight  = 20
+	marginTop    = 40
+	marginBottom = 50
+)
+
+// Convergence plots the cost of every evaluation of the run and the best
+// cost found up to it, with the passes marked, to show whether the search
+// plateaued. The cost axis goes up to the cost of the first evaluation,
+// evaluations that cost more are left out.
+func Convergence(w io.Writer, run *clangformat.Run) error {
+	c := newCanvas(convergenceWidth, convergenceHeight)
+
+	plotW := float64(convergenceWidth - marginLeft - marginRight)
+	plotH := float64(convergenceHeight - marginTop - marginBottom)
+
+	// the running best of the accepted evaluations
+	best := make([]int, len(run.Evaluations))
+	current, found := 0, false
+	for i, e := range run.Evaluations {
+		if !e.Failed() && (!found || e.Total.Weighted < current) {
+			current, found = e.Total.Weighted, true
+		}
+		best[i] = current
+	}
+
+	if !found {
+		c.text(convergenceWidth/2, convergenceHeight/2, "middle", "font-size: 14px",
+			"no evaluation was scored")
+		return c.writeTo(w)
+	}
+
+	first := 0
+	for first < len(run.Evaluations) && run.Evaluations[first].Failed() {
+		first++
+	}
+
+	yTicks := ticks(float64(best[len(best)-1]), float64(best[first]), 6)
+	lo, hi := yTicks[0], yTicks[len(yTicks)-1]
+
+	x := func(i int) float64 {
+		if len(run.Evaluations) < 2 {
+			return marginLeft + plotW/2
+		}
+
+		return marginLeft + plotW*float64(i)/float64(len(run.Evaluations)-1)
+	}
+	y := func(cost float64) float64 {
+		return marginTop + plotH - plotH*(cost-lo)/(hi-lo)
+	}
+
+	c.text(marginLeft, 24, "start", "font-size: 16px; font-weight: bold",
+		fmt.Sprintf("Best %s cost over %s", run.Metric, evaluations(len(run.Evaluations))))
+
+	for _, t := range yTicks {
+		c.line(marginLeft, y(t), marginLeft+plotW, y(t), "stroke: #eee")
+		c.text(marginLeft-8, y(t)+4, "end", "font-size: 11px; fill: #555", fmt.Sprintf("%.0f", t))
+	}
+
+	for _, t := range ticks(0, float64(len(run.Evaluations)-1), 8) {
+		if t > float64(len(run.Evaluations)-1) {
+			continue
+		}
+		c.text(x(int(t)), marginTop+plotH+18, "middle", "font-size: 11px; fill: #555", fmt.Sprintf("%.0f", t+1))
+	}
+	c.text(marginLeft+plotW/2, convergenceHeight-10, "middle", "font-size: 12px", "evaluation")
+
+	// the passes, from their first evaluation
+	pass := -1
+	for i, e := range run.Evaluations {
+		if e.Pass == pass {
+			continue
+		}
+
+		pass = e.Pass
+		label := fmt.Sprintf("pass %d", pass)
+		if pass == 0 {
+			label = "reference"
+		}
+
+		c.line(x(i), marginTop, x(i), marginTop+plotH, "stroke: #bbb; stroke-dasharray: 4 3")
+		c.text(x(i)+4, marginTop+12, "start", "font-size: 11px; fill: #555", label)
+	}
+
+	hidden := 0
+	for i, e := range run.Evaluations {
+		if e.Failed() {
+			continue
+		}
+		if float64(e.Total.Weighted) > hi {
+			hidden++
+			continue
+		}
+
+		c.titled(fmt.Sprintf("pass %d, %s: %s costs %d", e.Pass, e.Option, e.Value, e.Total.Weighted), func() {
+			c.circle(x(i), y(float64(e.Total.Weighted)), 2, "fill: #9ab; fill-opacity: 0.6")
+		})
+	}
+
+	points := make([]float64, 0, 2*len(best))
+	for i := first; i < len(best); i++ {
+		points = append(points, x(i), y(float64(best[i])))
+	}
+	c.polyline(points, "fill: none; stroke: #1f77b4; stroke-width: 2")
+
+	c.line(marginLeft, marginTop, marginLeft, marginTop+plotH, "stroke: #333")
+	c.line(marginLeft, marginTop+plotH, marginLeft+plotW, marginTop+plotH, "stroke: #333")
+
+	if hidden > 0 {
+		c.text(convergenceWidth-marginRight, 24, "end", "font-size: 11px; fill: #555",
+			fmt.Sprintf("not shown: %s costing more than %.0f", evaluations(hidden), hi))
+	}
+
+	return c.writeTo(w)
+}
+
+// evaluations counts n evaluations in words.
+func evaluations(n int) string {
+	if n == 1 {
+		return "1 evaluation"
+	}
+
+	return fmt.Sprintf("%d evaluations", n)
+}
@@ -0,0 +1,143 @@
+package chart
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+
+	clangformat "github.com/javorszky/go-diff-clang/pkg/clang-format"
+)
+
+const (
+	panelWidth   = 180
+	panelHeight  = 130
+	panelColumns = 5
+	panelPadding = 12
+
+	// panelTop and panelBottom leave room for the title and the values.
+	panelTop    = 34
+	panelBottom = 18
+
+	// charWidth is about how wide a character of the 9px labels is.
+	charWidth = 5.5
+)
+
+// panel is the chart of one option.
+type panel struct {
+	decision clangformat.Decision
+	values   []string
+	min      int
+	swing    int
+}
+
+// OptionCosts draws a small chart for every option, from the last pass that
+// checked it: a bar for every value, as high as its cost is above the
+// cheapest value's. Every chart has the same scale, and the options with the
+// largest swing come first.
+func OptionCosts(w io.Writer, run *clangformat.Run) error {
+	panels := make([]panel, 0)
+	maxSwing := 1
+	for _, d := range run.FinalDecisions() {
+		if len(d.Costs) < 2 {
+			continue
+		}
+
+		p := panel{decision: d, values: decisionValues(d)}
+		p.min = d.Costs[p.values[0]]
+		hi := p.min
+		for _, v := range p.values {
+			p.min = min(p.min, d.Costs[v])
+			hi = max(hi, d.Costs[v])
+		}
+		p.swing = hi - p.min
+		maxSwing = max(maxSwing, p.swing)
+
+		panels = append(panels, p)
+	}
+
+	slices.SortStableFunc(panels, func(a, b panel) int {
+		return cmp.Or(cmp.Compare(b.swing, a.swing), cmp.Compare(a.decision.Option, b.decision.Option))
+	})
+
+	rows := (len(panels) + panelColumns - 1) / panelColumns
+	c := newCanvas(panelColumns*panelWidth, 50+max(rows, 1)*panelHeight)
+
+	c.text(panelPadding, 24, "start", "font-size: 16px; font-weight: bold",
+		fmt.Sprintf("%s cost of every value above the cheapest, the largest swing being %d", run.Metric, maxSwing))
+	c.text(panelPadding, 40, "start", "font-size: 11px; fill: #555",
+		"the value the run chose is blue, options whose values all cost the same are greyed out")
+
+	for i, p := range panels {
+		drawPanel(c, p, 50+float64(i/panelColumns*panelHeight), float64(i%panelColumns*panelWidth), maxSwing)
+	}
+
+	return c.writeTo(w)
+}
+
+func drawPanel(c *canvas, p panel, top, left float64, maxSwing int) {
+	d := p.decision
+
+	titleStyle := "font-size: 10px; font-weight: bold"
+	if d.Irrelevant {
+		titleStyle = "font-size: 10px; font-weight: bold; fill: #999"
+	}
+
+	plotW := float64(panelWidth - 2*panelPadding)
+	maxChars := int(plotW / charWidth)
+	c.titled(d.Option, func() {
+		c.text(left+panelPadding, top+14, "start", titleStyle, truncate(d.Option, maxChars))
+	})
+	c.text(left+panelPadding, top+26, "start", "font-size: 9px; fill: #555",
+		fmt.Sprintf("pass %d, swing %d", d.Pass, p.swing))
+
+	plotH := float64(panelHeight - panelTop - panelBottom)
+	base := top + panelTop + plotH
+
+	slot := plotW / float64(len(p.values))
+	barW := max(slot*0.7, 1)
+
+	for i, v := range p.values {
+		cost := d.Costs[v]
+		h := max(plotH*float64(cost-p.min)/float64(maxSwing), 1)
+		x := left + panelPadding + float64(i)*slot + (slot-barW)/2
+
+		fill := "#bbb"
+		if v == d.Value {
+			fill = "#1f77b4"
+		}
+		if d.Irrelevant {
+			fill = "#ddd"
+		}
+
+		c.titled(fmt.Sprintf("%s: %s costs %d", d.Option, v, cost), func() {
+			c.rect(x, base-h, barW, h, "fill: "+fill)
+		})
+
+		c.text(x+barW/2, base+12, "middle", "font-size: 9px; fill: #333",
+			truncate(v, max(int(slot/charWidth), 1)))
+	}
+
+	c.line(left+panelPadding, base, left+panelPadding+plotW, base, "stroke: #333")
+}
+
+// decisionValues lists the values of a decision in the order of the option
+// catalog the pass went through, the way the run tried them.
+func decisionValues(d clangformat.Decision) []string {
+	values := make([]string, 0, len(d.Costs))
+	for v := range d.Costs {
+		values = append(values, v)
+	}
+
+	order := clangformat.Values(d.Pass, d.Option)
+	slices.SortFunc(values, func(a, b string) int {
+		ia, ib := slices.Index(order, a), slices.Index(order, b)
+		if ia < 0 || ib < 0 {
+			return cmp.Or(cmp.Compare(ib, ia), cmp.Compare(a, b))
+		}
+
+		return cmp.Compare(ia, ib)
+	})
+
+	return values
+}
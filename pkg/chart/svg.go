@@ -0,0 +1,130 @@
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// canvas collects the elements of an SVG document.
+type canvas struct {
+	width, height int
+	buf           bytes.Buffer
+}
+
+func newCanvas(width, height int) *canvas {
+	return &canvas{width: width, height: height}
+}
+
+func (c *canvas) line(x1, y1, x2, y2 float64, style string) {
+	fmt.Fprintf(&c.buf, `<line x1="%s" y1="%s" x2="%s" y2="%s" style="%s"/>`+"\n",
+		num(x1), num(y1), num(x2), num(y2), style)
+}
+
+func (c *canvas) rect(x, y, w, h float64, style string) {
+	fmt.Fprintf(&c.buf, `<rect x="%s" y="%s" width="%s" height="%s" style="%s"/>`+"\n",
+		num(x), num(y), num(w), num(h), style)
+}
+
+func (c *canvas) circle(x, y, r float64, style string) {
+	fmt.Fprintf(&c.buf, `<circle cx="%s" cy="%s" r="%s" style="%s"/>`+"\n", num(x), num(y), num(r), style)
+}
+
+// polyline joins the points, given as x, y pairs.
+func (c *canvas) polyline(points []float64, style string) {
+	c.buf.WriteString(`<polyline points="`)
+	for i := 0; i+1 < len(points); i += 2 {
+		if i > 0 {
+			c.buf.WriteString(" ")
+		}
+		c.buf.WriteString(num(points[i]) + "," + num(points[i+1]))
+	}
+	fmt.Fprintf(&c.buf, `" style="%s"/>`+"\n", style)
+}
+
+// text writes s at x, y. anchor is start, middle or end.
+func (c *canvas) text(x, y float64, anchor, style, s string) {
+	fmt.Fprintf(&c.buf, `<text x="%s" y="%s" text-anchor="%s" style="%s">%s</text>`+"\n",
+		num(x), num(y), anchor, style, html.EscapeString(s))
+}
+
+// titled wraps what title describes in a group with a tooltip.
+func (c *canvas) titled(title string, draw func()) {
+	fmt.Fprintf(&c.buf, "<g><title>%s</title>\n", html.EscapeString(title))
+	draw()
+	c.buf.WriteString("</g>\n")
+}
+
+func (c *canvas) writeTo(w io.Writer) error {
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d"`+
+		` style="font-family: sans-serif; background: #fff">`+"\n", c.width, c.height, c.width, c.height)
+	if err != nil {
+		return errors.Wrap(err, "writing svg")
+	}
+
+	_, err = w.Write(c.buf.Bytes())
+	if err != nil {
+		return errors.Wrap(err, "writing svg")
+	}
+
+	_, err = io.WriteString(w, "</svg>\n")
+	if err != nil {
+		return errors.Wrap(err, "writing svg")
+	}
+
+	return nil
+}
+
+// num formats a coordinate with at most two decimals.
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+// ticks returns round values from below lo to above hi, about n of them.
+func ticks(lo, hi float64, n int) []float64 {
+	if hi <= lo {
+		hi = lo + 1
+	}
+
+	step := niceStep((hi - lo) / float64(n))
+	out := make([]float64, 0, n+2)
+	for v := math.Floor(lo/step) * step; v < hi+step; v += step {
+		out = append(out, v)
+	}
+
+	return out
+}
+
+// niceStep rounds a step up to 1, 2 or 5 times a power of ten.
+func niceStep(raw float64) float64 {
+	if raw <= 0 {
+		return 1
+	}
+
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if raw <= m*magnitude {
+			return max(m*magnitude, 1)
+		}
+	}
+
+	return 10 * magnitude
+}
+
+// truncate shortens s to n characters, marking that it did.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n <= 1 {
+		return string(r[:n])
+	}
+
+	return string(r[:n-1]) + "…"
+}
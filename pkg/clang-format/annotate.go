@@ -0,0 +1,73 @@
+package clang_format
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Annotated renders the config of the run like String, each option the
+// search decided on followed by a comment with the cost of its value and of
+// every other value it tried, like
+//
+//	AlignArrayOfStructures: Left # Left=17666 Right=17840 None=18102
+//
+// Values clang-format rejected are listed as such, and options whose values
+// all cost the same are marked as not mattering. The costs are those of the
+// last decision on the option.
+func (r *Run) Annotated() string {
+	rejected := make(map[evaluationKey]bool)
+	for _, e := range r.Evaluations {
+		if e.Failed() {
+			rejected[evaluationKey{e.Pass, e.Option, e.Value}] = true
+		}
+	}
+
+	comments := make(map[string]string)
+	for _, d := range r.FinalDecisions() {
+		// the chosen value first, then the others in the order they were tried
+		values := []string{d.Value}
+		for _, v := range passCatalog(d.Pass)[d.Option] {
+			if v != d.Value && (rejected[evaluationKey{d.Pass, d.Option, v}] || hasCost(d, v)) {
+				values = append(values, v)
+			}
+		}
+		for _, v := range slices.Sorted(maps.Keys(d.Costs)) {
+			if !slices.Contains(values, v) {
+				values = append(values, v)
+			}
+		}
+		for _, e := range r.Evaluations {
+			if e.Pass == d.Pass && e.Option == d.Option && e.Failed() && !slices.Contains(values, e.Value) {
+				values = append(values, e.Value)
+			}
+		}
+
+		costs := make([]string, 0, len(values))
+		for _, v := range values {
+			if c, ok := d.Costs[v]; ok {
+				costs = append(costs, fmt.Sprintf("%s=%d", v, c))
+			} else {
+				costs = append(costs, v+"=rejected")
+			}
+		}
+
+		comment := strings.Join(costs, " ")
+		switch {
+		case d.Identical:
+			comment += " (doesn't matter: identical output)"
+		case d.Irrelevant:
+			comment += " (doesn't matter: same cost)"
+		}
+
+		comments[d.Option] = comment
+	}
+
+	return r.Format.render(comments)
+}
+
+func hasCost(d Decision, value string) bool {
+	_, ok := d.Costs[value]
+	return ok
+}
@@ -4,15 +4,19 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"log/slog"
+	"maps"
 	"math"
 	"os"
 	"os/exec"
 	"path"
 	"slices"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/javorszky/go-diff-clang/pkg/diff"
+	"github.com/javorszky/go-diff-clang/pkg/events"
+	"github.com/javorszky/go-diff-clang/pkg/metric"
 	"github.com/pkg/errors"
 )
 
@@ -20,8 +24,14 @@ const filename = ".clang-format"
 const dot = "."
 const TargetDirectory = "."
 const UnitDirectory = "unit/"
+const FilesList = "files.list"
 
-var errNoLinesChanged = errors.New("no lines changed")
+// optimizePasses is how many times the full option catalog is walked before
+// the doublecheck pass.
+const optimizePasses = 2
+
+// Passes is the number of passes a run makes, including the doublecheck.
+const Passes = optimizePasses + 1
 
 var bools = []string{"true", "false"}
 
@@ -172,6 +182,11 @@ var options = map[string][]string{
 type ClangFormat map[string]string
 
 func (c ClangFormat) String() string {
+	return c.render(nil)
+}
+
+// render writes c as YAML, the keys in comments followed by their comment.
+func (c ClangFormat) render(comments map[string]string) string {
 	groups := make(map[string]map[string]string)
 	lines := make(map[string]string)
 
@@ -206,7 +221,7 @@ func (c ClangFormat) String() string {
 	// Add the options to the buffer for the non-grouped options
 	for _, key := range linesAlphabetical {
 		buf.WriteString(
-			fmt.Sprintf("%s: %s\n", key, lines[key]),
+			fmt.Sprintf("%s: %s%s\n", key, lines[key], trailingComment(comments, key)),
 		)
 	}
 
@@ -242,9 +257,10 @@ func (c ClangFormat) String() string {
 		// Write the member options in alphabetical order into the
 		// buffer
 		for _, memberKey := range memberAlphabetical {
-			buf.WriteString(fmt.Sprintf("  %s: %s\n",
+			buf.WriteString(fmt.Sprintf("  %s: %s%s\n",
 				memberKey,
 				groups[groupKey][memberKey],
+				trailingComment(comments, groupKey+dot+memberKey),
 			))
 		}
 
@@ -256,167 +272,337 @@ func (c ClangFormat) String() string {
 	return buf.String()
 }
 
-func IdealClangFormatFile() (ClangFormat, int, error) {
-	format := generateBasic(options)
-	linesChangedTotal := math.MaxInt32
-	var err error
+func trailingComment(comments map[string]string, key string) string {
+	comment, ok := comments[key]
+	if !ok {
+		return ""
+	}
+
+	return " # " + comment
+}
+
+// Config holds the settings of a run that are not part of the option catalog.
+type Config struct {
+	// Events receives the machine-readable event stream. A nil Events
+	// discards them.
+	Events events.Sink
+
+	// Corpora are the repositories every candidate is scored on. With more
+	// than one, the cost of each is scaled to changes per million lines and
+	// weighted before they are added up. It defaults to DefaultCorpus.
+	Corpora []Corpus
+
+	// Metric scores the changes a candidate makes. It defaults to
+	// metric.ChangedLines.
+	Metric metric.Metric
+
+	// Weights multiply the cost of the files they match. Without any, every
+	// file counts the same.
+	Weights metric.FileWeights
+
+	// Objectives, when set, are scored on every candidate next to the cost,
+	// and the run keeps the Pareto front of the candidates it evaluated.
+	// Once the search settled an option, every value of it is tried on the
+	// points of the front as well, and what no point beats on every
+	// objective joins it, so the front moves towards the trade-offs and not
+	// only along the search's path.
+	Objectives []Objective
+
+	// FrontSize is how many points of the front, spread along it, get every
+	// option tried on them, which makes a run up to that many times longer.
+	// It defaults to 8.
+	FrontSize int
+
+	// Reference is a style to evaluate before the search starts, so the
+	// front includes it. It is meant for use with Distance.
+	Reference ClangFormat
+
+	// History, when set, gets every evaluation as it is made.
+	History *History
+
+	// TieBreak picks the winner among values that cost the same. It
+	// defaults to TieBreakFirst.
+	TieBreak TieBreak
+
+	// RecordDiffs keeps the diff of every file in the evaluations, for the
+	// run to be scored again with another metric without clang-format.
+	RecordDiffs bool
+}
+
+// withDefaults fills in what the caller left out. Every corpus gets its cache
+// of original files on first use and keeps it for later calls with the same
+// Config.
+func (cfg Config) withDefaults() Config {
+	if cfg.Events == nil {
+		cfg.Events = events.Discard{}
+	}
+	if len(cfg.Corpora) == 0 {
+		cfg.Corpora = []Corpus{DefaultCorpus()}
+	}
+	if cfg.Metric == nil {
+		cfg.Metric = metric.ChangedLines{}
+	}
+	if cfg.TieBreak == "" {
+		cfg.TieBreak = TieBreakFirst
+	}
+	if cfg.FrontSize == 0 {
+		cfg.FrontSize = defaultFrontSize
+	}
+
+	for i := range cfg.Corpora {
+		c := &cfg.Corpora[i]
+		if c.originals == nil {
+			c.originals = newOriginals(c.Dir)
+		}
+	}
+
+	return cfg
+}
+
+// IdealClangFormatFile searches for the options with the lowest cost on the
+// corpus. The run holds the winning format and every evaluation made on the
+// way. If ctx is cancelled the search stops, and the run so far is returned
+// together with an error wrapping ctx.Err().
+func IdealClangFormatFile(ctx context.Context, cfg Config) (*Run, error) {
+	cfg = cfg.withDefaults()
+
+	err := countCorpora(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		err := removeConfig()
+		if err != nil {
+			slog.Warn("could not remove generated config", "error", err)
+		}
+	}()
+
+	run := &Run{
+		Metric:      cfg.Metric.Name(),
+		TieBreak:    cfg.TieBreak,
+		Format:      generateBasic(options),
+		Total:       metric.Total{Raw: math.MaxInt32, Weighted: math.MaxInt32},
+		Evaluations: make([]Evaluation, 0, CandidateCount()),
+		tried:       make(map[string]bool),
+	}
+	defer func() { sortFront(run.Front) }()
+
+	if len(cfg.Objectives) > 0 {
+		run.Objectives = append(run.Objectives, cfg.Metric.Name())
+		for _, o := range cfg.Objectives {
+			run.Objectives = append(run.Objectives, o.Name())
+		}
+	}
 
-	for j := 0; j < 2; j++ {
-		fmt.Printf("Running iteration %d\n", j+1)
+	if cfg.Reference != nil {
+		slog.Info("evaluating the reference style")
 
-		format, linesChangedTotal, err = optimizeOptions(format, options, linesChangedTotal)
+		err := evaluateReference(ctx, cfg, run)
 		if err != nil {
-			return nil, 0, errors.Wrapf(err, "optimizeOptions in iteration %d", j)
+			return run, errors.Wrap(err, "evaluateReference")
+		}
+	}
+
+	for j := 0; j < optimizePasses; j++ {
+		slog.Info("starting pass", "pass", j+1)
+
+		err := optimizeOptions(ctx, cfg, run, j+1, options)
+		if err != nil {
+			return run, errors.Wrapf(err, "optimizeOptions in iteration %d", j)
 		}
 	}
 
 	// Let's go around the doublecheckafter bits
-	fmt.Println("Running the doublechecks after")
+	slog.Info("starting pass", "pass", Passes, "doublecheck", true)
 
-	format, linesChangedTotal, err = optimizeOptions(format, doubleCheckAfter, linesChangedTotal)
+	err = optimizeOptions(ctx, cfg, run, Passes, doubleCheckAfter)
 	if err != nil {
-		return nil, 0, errors.Wrap(err, "optimizeOptions in doubleCheck")
+		return run, errors.Wrap(err, "optimizeOptions in doubleCheck")
 	}
 
-	return format, linesChangedTotal, nil
+	return run, nil
 }
 
-func runOption(option ClangFormat) (int, error) {
-	fmt.Println("Writing .clang-format file")
-
-	err := os.WriteFile(
-		path.Join(TargetDirectory, filename),
-		[]byte(option.String()),
-		0755,
-	)
+// evaluateReference scores the reference style as an evaluation of pass 0,
+// putting it on the front unless a candidate found later beats it.
+func evaluateReference(ctx context.Context, cfg Config, run *Run) error {
+	result, err := runOption(ctx, cfg, cfg.Reference)
 	if err != nil {
-		return 0, errors.Wrap(err, "os.WriteFile %04d")
+		return errors.Wrap(err, "runOption")
 	}
 
-	var stdErr strings.Builder
-	var stdOut strings.Builder
+	run.Evaluations = append(run.Evaluations, result)
+	run.tried[cfg.Reference.Hash()] = true
+	cfg.History.Record(run.Metric, cfg.Reference, result)
 
-	CFCtx, CFCxl := context.WithTimeout(
-		context.Background(),
-		10*time.Second,
-	)
-	defer CFCxl()
-
-	clangFormatCmd := exec.CommandContext(CFCtx,
-		"clang-format",
-		"-i",
-		"--style=file", // will find a .clang-format file going up the tree.
-		"--verbose",
-		"--files=files.list",
-	)
+	if result.Failed() {
+		slog.Warn("clang-format rejected the reference style",
+			"exit_code", result.ExitCode,
+			"error", result.Error,
+		)
+		return nil
+	}
 
-	clangFormatCmd.Stderr = &stdErr
-	// clangFormatCmd does not need stdOut
+	slog.Info("reference style", "cost", result.Total.Weighted, "scores", result.Scores)
 
-	fmt.Println("Running clang-format command")
-	err = clangFormatCmd.Run()
-	if err != nil {
-		return 0, errors.Wrapf(err, "clangFormatCmd.Run(): %s", stdErr.String())
+	if len(cfg.Objectives) > 0 {
+		run.Front = addToFront(run.Front, ParetoPoint{
+			Scores: result.Scores,
+			Format: maps.Clone(cfg.Reference),
+		})
 	}
 
-	// let's get the diff
-	diffCtx, diffCxl := context.WithTimeout(context.Background(), 10*time.Second)
-	defer diffCxl()
+	return nil
+}
+
+// CandidateCount returns how many evaluations a full run performs.
+func CandidateCount() int {
+	return optimizePasses*countCandidates(options) + countCandidates(doubleCheckAfter)
+}
 
-	// Let's reset both writers, even though stdOut was not used above, probably
-	stdOut.Reset()
-	stdErr.Reset()
+// countCandidates counts the values optimizeOptions evaluates for a catalog.
+// Options with a single value are skipped there, so they are here too.
+func countCandidates(options map[string][]string) int {
+	n := 0
+	for _, values := range options {
+		if len(values) >= 2 {
+			n += len(values)
+		}
+	}
 
-	diffCmd := exec.CommandContext(diffCtx,
-		"git",
-		"--no-pager",
-		"diff",
-		"--numstat",
-	)
-	diffCmd.Dir = UnitDirectory
-	diffCmd.Stdout = &stdOut
-	diffCmd.Stderr = &stdErr
+	return n
+}
 
-	fmt.Println("Getting diff")
-	err = diffCmd.Run()
+// runOption formats every corpus with option and scores the changes with the
+// configured metric. If clang-format rejects the candidate the evaluation
+// records its exit status instead of a cost.
+func runOption(ctx context.Context, cfg Config, option ClangFormat) (Evaluation, error) {
+	result, _, err := evaluate(ctx, cfg, option)
+
+	return result, err
+}
+
+// evaluate is runOption that also returns the files the candidate changed.
+func evaluate(ctx context.Context, cfg Config, option ClangFormat) (Evaluation, []FormattedFile, error) {
+	started := time.Now()
+
+	formatted, rejected, err := formatAll(ctx, cfg, option)
 	if err != nil {
-		return 0, errors.Wrapf(err, "diff: %s", stdErr.String())
+		return Evaluation{}, nil, err
+	}
+	if rejected != nil {
+		rejected.DurationMS = time.Since(started).Milliseconds()
+		return *rejected, nil, nil
+	}
+
+	result := Evaluation{
+		Categories: make(metric.CategoryCounts),
+		Files:      make([]FileResult, 0),
 	}
 
-	linesChanged, err := parseNumStat(stdOut.String())
-	if err != nil && !errors.Is(err, errNoLinesChanged) {
-		return 0, errors.Wrap(err, "parseNumStat")
+	multi := len(cfg.Corpora) > 1
+	files := make([]*metric.File, 0, len(formatted))
+	corpora := make([]CorpusResult, 0, len(cfg.Corpora))
+
+	for _, c := range cfg.Corpora {
+		// git tells us which files changed, the metric needs the full diff
+		// of each of them.
+		corpusFiles := make([]*metric.File, 0)
+		for _, f := range formatted {
+			if f.Corpus == c.Name {
+				corpusFiles = append(corpusFiles, metric.NewFile(f.Path, f.Original, f.Formatted))
+			}
+		}
+
+		costs := metric.Costs(cfg.Metric, cfg.Weights, corpusFiles)
+		corpora = append(corpora, CorpusResult{
+			Name:   c.Name,
+			Lines:  c.lines,
+			Weight: c.Weight,
+			Total:  metric.SumCosts(costs),
+		})
+
+		for i, f := range corpusFiles {
+			result.Categories.Add(metric.Categories(f))
+
+			fr := FileResult{
+				Path:    f.Path,
+				Added:   f.Stat.Added,
+				Removed: f.Stat.Removed,
+				Changed: max(f.Stat.Added, f.Stat.Removed),
+				Cost:    costs[i].Raw,
+				Weight:  cfg.Weights.Weight(f.Path),
+			}
+			if multi {
+				fr.Corpus = c.Name
+			}
+			if cfg.RecordDiffs {
+				fr.Diff = diff.Unified("a/"+f.Path, "b/"+f.Path, f.Hunks)
+			}
+
+			result.Files = append(result.Files, fr)
+		}
+
+		files = append(files, corpusFiles...)
+	}
+
+	result.OutputHash = outputHash(formatted)
+
+	result.Total = corpora[0].Total
+	if multi {
+		result.Total = combineCorpora(corpora)
+		result.Corpora = corpora
 	}
 
-	if errors.Is(err, errNoLinesChanged) {
-		fmt.Println("No lines changed apparently...")
+	if len(cfg.Objectives) > 0 {
+		result.Scores = objectiveScores(cfg, option, files, result.Total)
 	}
 
-	fmt.Printf("Got diff, lines changed is %d\n", linesChanged)
+	result.DurationMS = time.Since(started).Milliseconds()
 
+	slog.Debug("got diff",
+		"files_changed", len(files),
+		"cost", result.Total.Weighted,
+		"raw_cost", result.Total.Raw,
+		"categories", result.Categories,
+	)
+
+	return result, formatted, nil
+}
+
+// resetCorpus throws away every change clang-format made to the corpus.
+func resetCorpus(ctx context.Context, dir string) error {
 	resetCtx, resetCxl := context.WithTimeout(
-		context.Background(),
+		ctx,
 		10*time.Second,
 	)
 	defer resetCxl()
 
-	fmt.Printf("Resetting repository\n\n")
+	slog.Debug("resetting repository")
 	resetCmd := exec.CommandContext(resetCtx,
 		"git",
 		"--no-pager",
 		"reset",
 		"--hard",
 	)
-	resetCmd.Dir = UnitDirectory
-	err = resetCmd.Run()
+	resetCmd.Dir = dir
+	err := resetCmd.Run()
 	if err != nil {
-		return 0, errors.Wrap(err, "reset")
+		return errors.Wrap(err, "reset")
 	}
 
-	return linesChanged, nil
+	return nil
 }
 
-func parseNumStat(output string) (int, error) {
-	totalLinesChanged := 0
-
-	trimmed := strings.TrimSpace(output)
-	if len(trimmed) == 0 {
-		return 0, errNoLinesChanged
-	}
-
-	// Split the input by lines
-	lines := strings.Split(strings.TrimSpace(output), "\n")
-	if len(lines) == 0 {
-		return 0, errNoLinesChanged
-	}
-
-	for _, line := range lines {
-		// Split each line into columns
-		columns := strings.Fields(line)
-
-		// Convert the first and second columns to integers and sum them
-		linesAdded, err := strconv.Atoi(columns[0])
-		if err != nil {
-			return 0, errors.Wrapf(err, "failed to convert number %s on"+
-				" col1 to int in line %s", columns[0], line)
-		}
-
-		linesRemoved, err := strconv.Atoi(columns[1])
-		if err != nil {
-			return 0, errors.Wrapf(err, "failed to convert number %s on"+
-				" col2 to int in line %s", columns[1], line)
-		}
-
-		// get the larger number, the idea being that 6 lines added, 5 lines
-		// removed means 5 lines changed + 1 line added = 6 total lines changed.
-		if linesAdded > linesRemoved {
-			totalLinesChanged += linesAdded
-		} else {
-			totalLinesChanged += linesRemoved
-		}
+// removeConfig deletes the .clang-format file the candidates are written to.
+func removeConfig() error {
+	err := os.Remove(path.Join(TargetDirectory, filename))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "os.Remove")
 	}
 
-	return totalLinesChanged, nil
+	return nil
 }
 
 func didLinesChange(in map[string]int) bool {
@@ -450,8 +636,44 @@ var doubleCheckAfter = map[string][]string{
 	"TabWidth":                             {"2", "4"},
 }
 
-func optimizeOptions(baseFormat ClangFormat, options map[string][]string, linesChangedTotal int) (ClangFormat, int,
-	error) {
+// passCatalog returns the option catalog the search goes through in pass, the
+// order of its values being what TieBreakFirst goes by.
+func passCatalog(pass int) map[string][]string {
+	if pass == Passes {
+		return doubleCheckAfter
+	}
+
+	return options
+}
+
+// Values returns the values the search tries for option in pass, in the order
+// it tries them.
+func Values(pass int, option string) []string {
+	return slices.Clone(passCatalog(pass)[option])
+}
+
+// KnownValues returns every value the search tries for option in any pass,
+// in the order the earliest pass that tries it has them.
+func KnownValues(option string) []string {
+	out := make([]string, 0)
+	for pass := 1; pass <= Passes; pass++ {
+		for _, v := range passCatalog(pass)[option] {
+			if !slices.Contains(out, v) {
+				out = append(out, v)
+			}
+		}
+	}
+
+	return out
+}
+
+// optimizeOptions tries every value of every option on top of the run's format
+// and keeps the one with the lowest cost, recording every evaluation in the
+// run. On error the format only holds winners, the value that was being
+// evaluated is rolled back.
+func optimizeOptions(ctx context.Context, cfg Config, run *Run, pass int, options map[string][]string) error {
+	baseFormat := run.Format
+
 	// let's create a slice of option names
 	optionNames := make([]string, len(options))
 	i := 0
@@ -466,63 +688,238 @@ func optimizeOptions(baseFormat ClangFormat, options map[string][]string, linesC
 	irrelevant := make([]string, 0)
 
 	// for each option, let's check whether their individual values
-	// would produce a diff that has a lower changed line count.
+	// would produce a diff that has a lower cost.
 	for _, optionName := range optionNames {
-		fmt.Printf(""+
-			"==================%s\n"+
-			"Checking option '%s'\n", strings.Repeat("=", len(optionName)), optionName)
 		if len(options[optionName]) < 2 {
-			fmt.Printf("Option '%s' is too short\n", optionName)
+			slog.Debug("skipping option with a single value", "option", optionName)
 			continue
 		}
 
+		slog.Info("checking option", "pass", pass, "option", optionName)
+
+		// changes holds the weighted costs the winner is picked by, results
+		// the rest of what the evaluations found.
 		changes := make(map[string]int)
+		results := make(map[string]Evaluation)
+		hashes := make(map[string]bool)
+		previous, hadPrevious := baseFormat[optionName]
 
 		for _, value := range options[optionName] {
-			fmt.Printf("  Checking value\n"+
-				"  %s: %s\n", optionName, value)
+			if ctx.Err() != nil {
+				restoreOption(baseFormat, optionName, previous, hadPrevious)
+				return errors.Wrap(ctx.Err(), "interrupted")
+			}
+
+			slog.Debug("checking value", "option", optionName, "value", value)
 			if value == "" {
 				panic(fmt.Sprintf("why %s", optionName))
 			}
 			baseFormat[optionName] = value
 
-			linesChanged, err := runOption(baseFormat)
+			cfg.Events.Emit(events.EvaluationStarted{
+				Pass:   pass,
+				Option: optionName,
+				Value:  value,
+			})
+
+			result, err := runOption(ctx, cfg, baseFormat)
 			if err != nil {
-				return nil, 0, errors.Wrap(err, "runOption")
+				restoreOption(baseFormat, optionName, previous, hadPrevious)
+				if ctx.Err() != nil {
+					// clang-format or git was killed, that's not the interesting part
+					return errors.Wrap(ctx.Err(), "interrupted")
+				}
+
+				return errors.Wrap(err, "runOption")
 			}
 
-			changes[value] = linesChanged
+			result.Pass, result.Option, result.Value = pass, optionName, value
+			run.Evaluations = append(run.Evaluations, result)
+			run.tried[baseFormat.Hash()] = true
+			cfg.History.Record(run.Metric, baseFormat, result)
+
+			cfg.Events.Emit(events.EvaluationFinished{
+				Pass:       pass,
+				Option:     optionName,
+				Value:      value,
+				Cost:       result.Total.Weighted,
+				RawCost:    result.Total.Raw,
+				Categories: categoryNames(result.Categories),
+				DurationMS: result.DurationMS,
+				ExitCode:   result.ExitCode,
+			})
+
+			if result.Failed() {
+				slog.Warn("clang-format rejected value, skipping it",
+					"option", optionName,
+					"value", value,
+					"exit_code", result.ExitCode,
+					"error", result.Error,
+				)
+				continue
+			}
+
+			changes[value] = result.Total.Weighted
+			results[value] = result
+			hashes[result.OutputHash] = true
+
+			if len(cfg.Objectives) > 0 {
+				run.Front = addToFront(run.Front, ParetoPoint{
+					Scores: result.Scores,
+					Format: maps.Clone(baseFormat),
+					Pass:   pass,
+					Option: optionName,
+					Value:  value,
+				})
+			}
+		}
+
+		if len(changes) == 0 {
+			restoreOption(baseFormat, optionName, previous, hadPrevious)
+			return errors.Errorf("clang-format rejected every value of %s", optionName)
 		}
 
-		if didLinesChange(changes) {
+		isIrrelevant := didLinesChange(changes)
+		isIdentical := len(hashes) == 1
+		if isIrrelevant {
 			irrelevant = append(irrelevant, optionName)
 		}
 
-		minLinesChanged := math.MaxInt32
-		winningValue := ""
-		for value, linesChanged := range changes {
-			if linesChanged < minLinesChanged {
-				winningValue = value
-				minLinesChanged = linesChanged
+		winningValue, minCost := cfg.TieBreak.winner(options[optionName], changes, previous)
+
+		slog.Info("option winner",
+			"pass", pass,
+			"option", optionName,
+			"value", winningValue,
+			"cost", minCost,
+			"raw_cost", results[winningValue].Total.Raw,
+		)
+		cfg.Events.Emit(events.OptionWinner{
+			Pass:       pass,
+			Option:     optionName,
+			Value:      winningValue,
+			Cost:       minCost,
+			RawCost:    results[winningValue].Total.Raw,
+			Irrelevant: isIrrelevant,
+			Identical:  isIdentical,
+		})
+
+		run.Decisions = append(run.Decisions, Decision{
+			Pass:       pass,
+			Option:     optionName,
+			Value:      winningValue,
+			Costs:      changes,
+			Irrelevant: isIrrelevant,
+			Identical:  isIdentical,
+		})
+
+		if run.Total.Weighted > minCost {
+			run.Total = results[winningValue].Total
+			run.Corpora = results[winningValue].Corpora
+		}
+
+		baseFormat[optionName] = winningValue
+
+		if len(cfg.Objectives) > 0 {
+			err := refineFront(ctx, cfg, run, pass, optionName, options[optionName])
+			if err != nil {
+				return errors.Wrap(err, "refineFront")
 			}
 		}
+	}
 
-		fmt.Printf(""+
-			"* Winning value was '%s' *\n"+
-			"* with lines changed %d *\n",
-			winningValue,
-			minLinesChanged,
-		)
+	slog.Info("pass finished",
+		"pass", pass,
+		"cost", run.Total.Weighted,
+		"raw_cost", run.Total.Raw,
+		"irrelevant", irrelevant,
+	)
+	run.Passes = append(run.Passes, PassResult{
+		Pass:       pass,
+		Total:      run.Total,
+		Irrelevant: irrelevant,
+	})
+	cfg.Events.Emit(events.PassFinished{
+		Pass:       pass,
+		Cost:       run.Total.Weighted,
+		RawCost:    run.Total.Raw,
+		Irrelevant: irrelevant,
+	})
+
+	return nil
+}
+
+// defaultFrontSize is how many points of the front get refined unless the
+// config says otherwise.
+const defaultFrontSize = 8
+
+// refineFront tries every value of the option on up to FrontSize points of
+// the front, skipping the configs already evaluated, and adds what no point
+// beats to the front. The evaluations go to the history, not the run: they
+// are not part of the search's decisions.
+func refineFront(ctx context.Context, cfg Config, run *Run, pass int, optionName string, values []string) error {
+	for _, p := range thinFront(slices.Clone(run.Front), cfg.FrontSize) {
+		for _, value := range values {
+			format := maps.Clone(p.Format)
+			format[optionName] = value
+
+			hash := format.Hash()
+			if run.tried[hash] {
+				continue
+			}
+			run.tried[hash] = true
 
-		if linesChangedTotal > minLinesChanged {
-			linesChangedTotal = minLinesChanged
+			if ctx.Err() != nil {
+				return errors.Wrap(ctx.Err(), "interrupted")
+			}
+
+			slog.Debug("refining the front", "option", optionName, "value", value, "scores", p.Scores)
+
+			result, err := runOption(ctx, cfg, format)
+			if err != nil {
+				if ctx.Err() != nil {
+					return errors.Wrap(ctx.Err(), "interrupted")
+				}
+
+				return errors.Wrap(err, "runOption")
+			}
+
+			result.Pass, result.Option, result.Value = pass, optionName, value
+			cfg.History.Record(run.Metric, format, result)
+
+			if result.Failed() {
+				continue
+			}
+
+			run.Front = addToFront(run.Front, ParetoPoint{
+				Scores: result.Scores,
+				Format: format,
+				Pass:   pass,
+				Option: optionName,
+				Value:  value,
+			})
 		}
+	}
 
-		baseFormat[optionName] = winningValue
+	return nil
+}
+
+func restoreOption(format ClangFormat, optionName, previous string, hadPrevious bool) {
+	if hadPrevious {
+		format[optionName] = previous
+		return
 	}
 
-	fmt.Printf("These options did not have an effect on number of lines"+
-		"changed whatever their value was: %v\n", irrelevant)
+	delete(format, optionName)
+}
+
+// categoryNames turns category counts into plain strings for the event
+// stream, which knows nothing about metrics.
+func categoryNames(in metric.CategoryCounts) map[string]int {
+	out := make(map[string]int, len(in))
+	for k, v := range in {
+		out[string(k)] = v
+	}
 
-	return baseFormat, linesChangedTotal, nil
+	return out
 }
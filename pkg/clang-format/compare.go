@@ -0,0 +1,169 @@
+package clang_format
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// KeyDifference is a key two configs set differently. A config that does not
+// set the key has an empty value.
+type KeyDifference struct {
+	Key string `json:"key"`
+	A   string `json:"a"`
+	B   string `json:"b"`
+}
+
+// FileComparison is the cost of one file under two configs, after file
+// weights. A config that left the file alone costs nothing for it.
+type FileComparison struct {
+	Corpus string  `json:"corpus,omitempty"`
+	Path   string  `json:"path"`
+	A      float64 `json:"a"`
+	B      float64 `json:"b"`
+}
+
+// Delta is how much more the file costs under B.
+func (f FileComparison) Delta() float64 {
+	return f.B - f.A
+}
+
+// Comparison is two configs scored against the same corpora.
+type Comparison struct {
+	A Evaluation `json:"a"`
+	B Evaluation `json:"b"`
+
+	// Keys are the keys the configs set differently, in key order.
+	Keys []KeyDifference `json:"keys"`
+
+	// Costs has every file either config changed, the files whose cost
+	// differs the most first.
+	Costs []FileComparison `json:"costs"`
+
+	// Files are the files the configs format differently, the most changed
+	// first. The diffs go from the output of A to that of B.
+	Files []FileDiff `json:"-"`
+}
+
+// Compare scores a and b against the corpora and compares their costs and
+// their output. It is an error if clang-format rejects either.
+func Compare(ctx context.Context, cfg Config, a, b ClangFormat) (*Comparison, error) {
+	evaluations, files, err := evaluateBoth(ctx, cfg, a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, e := range evaluations {
+		if e.Failed() {
+			return nil, errors.Errorf("clang-format rejected config %c with exit status %d: %s", 'A'+i,
+				e.ExitCode, e.Error)
+		}
+	}
+
+	return &Comparison{
+		A:     evaluations[0],
+		B:     evaluations[1],
+		Keys:  keyDifferences(a, b),
+		Costs: compareFiles(evaluations[0].Files, evaluations[1].Files),
+		Files: files,
+	}, nil
+}
+
+// evaluateBoth formats the corpora with a and with b, scores both and
+// compares their output, the most changed files first. When clang-format
+// rejects either config there are no files to compare.
+func evaluateBoth(ctx context.Context, cfg Config, a, b ClangFormat) ([2]Evaluation, []FileDiff, error) {
+	cfg = cfg.withDefaults()
+
+	var evaluations [2]Evaluation
+
+	err := countCorpora(cfg)
+	if err != nil {
+		return evaluations, nil, err
+	}
+
+	defer func() {
+		err := removeConfig()
+		if err != nil {
+			slog.Warn("could not remove generated config", "error", err)
+		}
+	}()
+
+	var outputs [2][]FormattedFile
+	for i, format := range []ClangFormat{a, b} {
+		e, formatted, err := evaluate(ctx, cfg, format)
+		if err != nil {
+			return evaluations, nil, err
+		}
+
+		cfg.History.Record(cfg.Metric.Name(), format, e)
+
+		evaluations[i], outputs[i] = e, formatted
+	}
+
+	if evaluations[0].Failed() || evaluations[1].Failed() {
+		return evaluations, nil, nil
+	}
+
+	files := DiffFormatted(outputs[0], outputs[1])
+	sortBySize(files)
+
+	return evaluations, files, nil
+}
+
+// keyDifferences lists the keys a and b set differently.
+func keyDifferences(a, b ClangFormat) []KeyDifference {
+	keys := slices.Sorted(maps.Keys(a))
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	out := make([]KeyDifference, 0)
+	for _, k := range keys {
+		if a[k] != b[k] {
+			out = append(out, KeyDifference{Key: k, A: a[k], B: b[k]})
+		}
+	}
+
+	return out
+}
+
+// compareFiles pairs up the per-file costs of two evaluations.
+func compareFiles(a, b []FileResult) []FileComparison {
+	type key struct{ corpus, path string }
+
+	index := make(map[key]int)
+	out := make([]FileComparison, 0, len(a))
+	for _, f := range a {
+		index[key{f.Corpus, f.Path}] = len(out)
+		out = append(out, FileComparison{Corpus: f.Corpus, Path: f.Path, A: f.Cost * f.Weight})
+	}
+	for _, f := range b {
+		i, ok := index[key{f.Corpus, f.Path}]
+		if !ok {
+			i = len(out)
+			out = append(out, FileComparison{Corpus: f.Corpus, Path: f.Path})
+		}
+
+		out[i].B = f.Cost * f.Weight
+	}
+
+	slices.SortStableFunc(out, func(x, y FileComparison) int {
+		return cmp.Or(
+			cmp.Compare(math.Abs(y.Delta()), math.Abs(x.Delta())),
+			strings.Compare(x.Corpus, y.Corpus),
+			strings.Compare(x.Path, y.Path),
+		)
+	})
+
+	return out
+}
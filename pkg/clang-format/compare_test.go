@@ -0,0 +1,43 @@
+package clang_format
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeyDifferences(t *testing.T) {
+	a := ClangFormat{"IndentWidth": "4", "UseTab": "Never", "ColumnLimit": "100"}
+	b := ClangFormat{"IndentWidth": "2", "UseTab": "Never", "TabWidth": "4"}
+
+	want := []KeyDifference{
+		{Key: "ColumnLimit", A: "100"},
+		{Key: "IndentWidth", A: "4", B: "2"},
+		{Key: "TabWidth", B: "4"},
+	}
+
+	if got := keyDifferences(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("keyDifferences() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCompareFiles(t *testing.T) {
+	a := []FileResult{
+		{Path: "a.c", Cost: 4, Weight: 1},
+		{Path: "b.c", Cost: 10, Weight: 0.5},
+	}
+	b := []FileResult{
+		{Path: "b.c", Cost: 10, Weight: 0.5},
+		{Path: "c.c", Cost: 7, Weight: 1},
+		{Path: "a.c", Cost: 2, Weight: 1},
+	}
+
+	want := []FileComparison{
+		{Path: "c.c", A: 0, B: 7},
+		{Path: "a.c", A: 4, B: 2},
+		{Path: "b.c", A: 5, B: 5},
+	}
+
+	if got := compareFiles(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("compareFiles() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,155 @@
+package clang_format
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+	"github.com/pkg/errors"
+)
+
+// normalizedLines is the corpus size costs are scaled to when several
+// corpora are scored together: changes per million lines keep whole numbers
+// where changes per thousand would round most differences away.
+const normalizedLines = 1_000_000
+
+// Corpus is one git repository the candidates are scored on.
+type Corpus struct {
+	// Name tells the corpus apart in results, it defaults to the base name
+	// of Dir.
+	Name string
+
+	// Dir is the repository that gets formatted and reset.
+	Dir string
+
+	// FilesList lists the files clang-format is run on, relative to the
+	// working directory.
+	FilesList string
+
+	// Weight multiplies the normalized cost of the corpus when there are
+	// several. It defaults to 1.
+	Weight float64
+
+	lines     int
+	originals *originals
+}
+
+// DefaultCorpus is the corpus a run uses when it is not given any.
+func DefaultCorpus() Corpus {
+	return Corpus{Name: "unit", Dir: UnitDirectory, FilesList: FilesList, Weight: 1}
+}
+
+// ParseCorpus parses a dir or dir=weight corpus flag.
+func ParseCorpus(s string) (Corpus, error) {
+	dir, weight, hasWeight := strings.Cut(s, "=")
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return Corpus{}, errors.Errorf("corpus %q has no directory", s)
+	}
+
+	c := Corpus{Dir: dir, Weight: 1}
+	if hasWeight {
+		w, err := strconv.ParseFloat(strings.TrimSpace(weight), 64)
+		if err != nil {
+			return Corpus{}, errors.Wrapf(err, "weight of corpus %q", s)
+		}
+		if w < 0 {
+			return Corpus{}, errors.Errorf("weight of corpus %q is negative", s)
+		}
+
+		c.Weight = w
+	}
+
+	c.Name = filepath.Base(filepath.Clean(dir))
+	c.FilesList = "files." + c.Name + ".list"
+
+	return c, nil
+}
+
+// CorpusResult is the cost of a candidate on one corpus.
+type CorpusResult struct {
+	Name   string       `json:"name"`
+	Lines  int          `json:"lines"`
+	Weight float64      `json:"weight"`
+	Total  metric.Total `json:"total"`
+}
+
+// Normalized is the cost per million lines of the corpus, not weighted yet.
+func (r CorpusResult) Normalized() float64 {
+	if r.Lines == 0 {
+		return 0
+	}
+
+	return float64(r.Total.Weighted) * normalizedLines / float64(r.Lines)
+}
+
+// combineCorpora scales the cost of every corpus to the same size, weighs it,
+// and adds them up.
+func combineCorpora(results []CorpusResult) metric.Total {
+	raw, weighted := 0.0, 0.0
+	for _, r := range results {
+		if r.Lines == 0 {
+			continue
+		}
+
+		scale := r.Weight * normalizedLines / float64(r.Lines)
+		raw += float64(r.Total.Raw) * scale
+		weighted += float64(r.Total.Weighted) * scale
+	}
+
+	return metric.SumCosts([]metric.Cost{{Raw: raw, Weighted: weighted}})
+}
+
+// countCorpora counts the lines of every corpus when there are several, for
+// their costs to be normalized by.
+func countCorpora(cfg Config) error {
+	if len(cfg.Corpora) < 2 {
+		return nil
+	}
+
+	for i := range cfg.Corpora {
+		c := &cfg.Corpora[i]
+
+		lines, err := countLines(c.FilesList)
+		if err != nil {
+			return errors.Wrapf(err, "counting lines of %s", c.Name)
+		}
+
+		c.lines = lines
+		slog.Info("corpus", "name", c.Name, "lines", lines, "weight", c.Weight)
+	}
+
+	return nil
+}
+
+// countLines counts the lines of every file in a files list.
+func countLines(list string) (int, error) {
+	raw, err := os.ReadFile(list)
+	if err != nil {
+		return 0, errors.Wrap(err, "os.ReadFile")
+	}
+
+	total := 0
+	for _, f := range strings.Split(string(raw), "\n") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+
+		content, err := os.ReadFile(f)
+		if err != nil {
+			return 0, errors.Wrapf(err, "reading %s", f)
+		}
+
+		total += bytes.Count(content, []byte("\n"))
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			total++
+		}
+	}
+
+	return total, nil
+}
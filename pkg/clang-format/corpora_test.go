@@ -0,0 +1,48 @@
+package clang_format
+
+import (
+	"testing"
+
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+)
+
+func TestParseCorpus(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Corpus
+		wantErr bool
+	}{
+		{spec: "unit/", want: Corpus{Name: "unit", Dir: "unit/", FilesList: "files.unit.list", Weight: 1}},
+		{spec: "../njs=2.5", want: Corpus{Name: "njs", Dir: "../njs", FilesList: "files.njs.list", Weight: 2.5}},
+		{spec: "=2", wantErr: true},
+		{spec: "njs=heavy", wantErr: true},
+		{spec: "njs=-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseCorpus(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCorpus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseCorpus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_combineCorpora(t *testing.T) {
+	got := combineCorpora([]CorpusResult{
+		// 100 per million lines, weighed twice
+		{Name: "a", Lines: 200_000, Weight: 2, Total: metric.Total{Raw: 40, Weighted: 20}},
+		// 500 per million lines
+		{Name: "b", Lines: 10_000, Weight: 1, Total: metric.Total{Raw: 5, Weighted: 5}},
+		// empty corpora don't count
+		{Name: "c", Lines: 0, Weight: 1, Total: metric.Total{Raw: 5, Weighted: 5}},
+	})
+
+	want := metric.Total{Raw: 2*200 + 500, Weighted: 2*100 + 500}
+	if got != want {
+		t.Errorf("combineCorpora() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,70 @@
+package clang_format
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+	"github.com/pkg/errors"
+)
+
+// Explanation is what switching one option of a config from one value to
+// another does to the formatted corpora.
+type Explanation struct {
+	Option string
+	From   string
+	To     string
+
+	// FromTotal and ToTotal are the costs of the config with either value.
+	FromTotal metric.Total
+	ToTotal   metric.Total
+
+	// Files are the files the two values format differently, the most
+	// changed first. The diffs go from the output of From to that of To.
+	Files []FileDiff
+}
+
+// Explain formats the corpora with format, option set to from and then to,
+// and compares the two outputs. It is an error if clang-format rejects
+// either.
+func Explain(ctx context.Context, cfg Config, format ClangFormat, option, from, to string) (*Explanation, error) {
+	a, b := maps.Clone(format), maps.Clone(format)
+	a[option], b[option] = from, to
+
+	evaluations, files, err := evaluateBoth(ctx, cfg, a, b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "formatting with %s", option)
+	}
+
+	for i, value := range []string{from, to} {
+		e := evaluations[i]
+		if e.Failed() {
+			return nil, errors.Errorf("clang-format rejected %s: %s with exit status %d: %s", option, value,
+				e.ExitCode, e.Error)
+		}
+	}
+
+	return &Explanation{
+		Option:    option,
+		From:      from,
+		To:        to,
+		FromTotal: evaluations[0].Total,
+		ToTotal:   evaluations[1].Total,
+		Files:     files,
+	}, nil
+}
+
+// sortBySize puts the files with the most changed lines first, keeping
+// files that changed as much in corpus and path order.
+func sortBySize(files []FileDiff) {
+	slices.SortStableFunc(files, func(a, b FileDiff) int {
+		return cmp.Or(
+			cmp.Compare(b.Size(), a.Size()),
+			strings.Compare(a.Corpus, b.Corpus),
+			strings.Compare(a.Path, b.Path),
+		)
+	})
+}
@@ -0,0 +1,59 @@
+package clang_format
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// sixteenLines is the numbers 1 to 16, one a line, with the lines in
+// changes replaced.
+func sixteenLines(changes map[int]string) []byte {
+	var b strings.Builder
+	for i := 1; i <= 16; i++ {
+		line, ok := changes[i]
+		if !ok {
+			line = strconv.Itoa(i)
+		}
+		b.WriteString(line + "\n")
+	}
+
+	return []byte(b.String())
+}
+
+func TestDiffFormatted_bySize(t *testing.T) {
+	original := sixteenLines(nil)
+
+	from := []FormattedFile{
+		{Corpus: "unit", Path: "a.c", Original: original, Formatted: sixteenLines(map[int]string{1: "x"})},
+		{Corpus: "unit", Path: "same.c", Original: original, Formatted: []byte("same\n")},
+	}
+	to := []FormattedFile{
+		{Corpus: "unit", Path: "same.c", Original: original, Formatted: []byte("same\n")},
+		// b.c only changes with the second value, in a small and a big hunk
+		{Corpus: "unit", Path: "b.c", Original: original,
+			Formatted: sixteenLines(map[int]string{1: "x", 13: "y", 14: "z", 15: "w"})},
+	}
+
+	files := DiffFormatted(from, to)
+	sortBySize(files)
+
+	if len(files) != 2 {
+		t.Fatalf("DiffFormatted() = %d files, want a.c and b.c", len(files))
+	}
+	if files[0].Path != "b.c" || files[0].Size() != 8 || files[1].Path != "a.c" || files[1].Size() != 2 {
+		t.Errorf("sorted files = %s %d, %s %d, want b.c 8, a.c 2", files[0].Path, files[0].Size(),
+			files[1].Path, files[1].Size())
+	}
+
+	hunks := files[0].LargestHunks(1)
+	if len(hunks) != 1 || hunks[0].OldStart != 10 {
+		t.Errorf("LargestHunks(1) = %+v, want the hunk at line 10", hunks)
+	}
+	if got := files[0].LargestHunks(5); len(got) != 2 {
+		t.Errorf("LargestHunks(5) = %d hunks, want 2", len(got))
+	}
+	if got := files[0].LargestHunks(-1); len(got) != 0 {
+		t.Errorf("LargestHunks(-1) = %d hunks, want none", len(got))
+	}
+}
@@ -0,0 +1,117 @@
+package clang_format
+
+import (
+	"encoding/csv"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// FinalEvaluation returns the evaluation of the config the run ended with:
+// the one that won the last decision. It reports false for a run that made
+// no decision.
+func (r *Run) FinalEvaluation() (Evaluation, bool) {
+	if len(r.Decisions) == 0 {
+		return Evaluation{}, false
+	}
+
+	last := r.Decisions[len(r.Decisions)-1]
+	for _, e := range slices.Backward(r.Evaluations) {
+		if e.Pass == last.Pass && e.Option == last.Option && e.Value == last.Value {
+			return e, true
+		}
+	}
+
+	return Evaluation{}, false
+}
+
+// WriteCostsCSV writes a row for every value of every option in every pass:
+// its cost, the cost before file weights, whether it won, and whether the
+// option was irrelevant in that pass. Values clang-format rejected are
+// included without a cost.
+func WriteCostsCSV(w io.Writer, run *Run) error {
+	evaluations := make(map[evaluationKey]Evaluation, len(run.Evaluations))
+	for _, e := range run.Evaluations {
+		evaluations[evaluationKey{e.Pass, e.Option, e.Value}] = e
+	}
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"pass", "option", "value", "cost", "raw_cost", "winner", "irrelevant", "exit_code"})
+
+	for _, d := range run.Decisions {
+		values := make([]string, 0)
+		for _, v := range passCatalog(d.Pass)[d.Option] {
+			if _, ok := evaluations[evaluationKey{d.Pass, d.Option, v}]; ok {
+				values = append(values, v)
+			}
+		}
+		for _, v := range slices.Sorted(maps.Keys(d.Costs)) {
+			if !slices.Contains(values, v) {
+				values = append(values, v)
+			}
+		}
+
+		for _, v := range values {
+			e := evaluations[evaluationKey{d.Pass, d.Option, v}]
+
+			cost, rawCost := "", ""
+			if c, ok := d.Costs[v]; ok {
+				cost = strconv.Itoa(c)
+				rawCost = strconv.Itoa(e.Total.Raw)
+			}
+
+			_ = cw.Write([]string{
+				strconv.Itoa(d.Pass),
+				d.Option,
+				v,
+				cost,
+				rawCost,
+				strconv.FormatBool(v == d.Value),
+				strconv.FormatBool(d.Irrelevant),
+				strconv.Itoa(e.ExitCode),
+			})
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return errors.Wrap(err, "writing costs")
+	}
+
+	return nil
+}
+
+// WriteFilesCSV writes a row for every file the final config changes, with
+// its line counts, cost and weight.
+func WriteFilesCSV(w io.Writer, run *Run) error {
+	final, ok := run.FinalEvaluation()
+	if !ok {
+		return errors.New("the run has no final evaluation")
+	}
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"corpus", "path", "added", "removed", "changed", "cost", "weight", "weighted_cost"})
+
+	for _, f := range final.Files {
+		_ = cw.Write([]string{
+			f.Corpus,
+			f.Path,
+			strconv.Itoa(f.Added),
+			strconv.Itoa(f.Removed),
+			strconv.Itoa(f.Changed),
+			strconv.FormatFloat(f.Cost, 'f', -1, 64),
+			strconv.FormatFloat(f.Weight, 'f', -1, 64),
+			strconv.FormatFloat(f.Cost*f.Weight, 'f', -1, 64),
+		})
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return errors.Wrap(err, "writing files")
+	}
+
+	return nil
+}
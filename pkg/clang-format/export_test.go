@@ -0,0 +1,53 @@
+package clang_format
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteCostsCSV(t *testing.T) {
+	run := recordedRun()
+	run.Evaluations = append(run.Evaluations, Evaluation{Pass: 1, Option: "AlignAfterOpenBracket",
+		Value: "AlwaysBreak", ExitCode: 1, Error: "invalid"})
+	run.Decisions[1].Costs["false"] = 6
+
+	buf := bytes.Buffer{}
+	err := WriteCostsCSV(&buf, run)
+	if err != nil {
+		t.Fatalf("WriteCostsCSV() error = %v", err)
+	}
+
+	// the rejected value has no cost, and weights set false's cost apart
+	// from its raw one
+	want := "pass,option,value,cost,raw_cost,winner,irrelevant,exit_code\n" +
+		"1,AlignAfterOpenBracket,Align,2,2,false,false,0\n" +
+		"1,AlignAfterOpenBracket,DontAlign,2,2,true,false,0\n" +
+		"1,AlignAfterOpenBracket,AlwaysBreak,,,false,false,1\n" +
+		"1,SpaceAfterCStyleCast,true,2,2,true,false,0\n" +
+		"1,SpaceAfterCStyleCast,false,6,3,false,false,0\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteCostsCSV() wrote\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteFilesCSV(t *testing.T) {
+	run := recordedRun()
+	run.Evaluations[2].Files[0].Weight = 2
+
+	buf := bytes.Buffer{}
+	err := WriteFilesCSV(&buf, run)
+	if err != nil {
+		t.Fatalf("WriteFilesCSV() error = %v", err)
+	}
+
+	// true won the last decision
+	want := "corpus,path,added,removed,changed,cost,weight,weighted_cost\n" +
+		",src/a.c,2,2,2,2,2,4\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteFilesCSV() wrote\n%s\nwant\n%s", got, want)
+	}
+
+	if err := WriteFilesCSV(&buf, &Run{}); err == nil {
+		t.Errorf("WriteFilesCSV() of a run without decisions returned no error")
+	}
+}
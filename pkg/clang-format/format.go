@@ -0,0 +1,321 @@
+package clang_format
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/javorszky/go-diff-clang/pkg/diff"
+	"github.com/pkg/errors"
+)
+
+// FormattedFile is a file a config changed, before and after formatting.
+type FormattedFile struct {
+	Corpus string
+	// Path is relative to the corpus root.
+	Path      string
+	Original  []byte
+	Formatted []byte
+}
+
+// Format formats every corpus with format and returns the files it changed.
+// The corpora are reset afterwards. It is an error if clang-format rejects
+// the config.
+func Format(ctx context.Context, cfg Config, format ClangFormat) ([]FormattedFile, error) {
+	cfg = cfg.withDefaults()
+
+	defer func() {
+		err := removeConfig()
+		if err != nil {
+			slog.Warn("could not remove generated config", "error", err)
+		}
+	}()
+
+	files, rejected, err := formatAll(ctx, cfg, format)
+	if err != nil {
+		return nil, err
+	}
+	if rejected != nil {
+		return nil, errors.Errorf("clang-format rejected the config with exit status %d: %s",
+			rejected.ExitCode, rejected.Error)
+	}
+
+	return files, nil
+}
+
+// formatAll writes format where clang-format is told to find it, formats
+// every corpus with it and collects what changed. The corpora are reset
+// afterwards whatever happened, even if ctx was cancelled half way through.
+func formatAll(ctx context.Context, cfg Config, format ClangFormat) (files []FormattedFile, rejected *Evaluation,
+	err error) {
+	defer func() {
+		for _, c := range cfg.Corpora {
+			resetErr := resetCorpus(context.WithoutCancel(ctx), c.Dir)
+			if resetErr != nil && err == nil {
+				files, rejected, err = nil, nil, errors.Wrapf(resetErr, "resetCorpus %s", c.Name)
+			}
+		}
+	}()
+
+	slog.Debug("writing .clang-format file")
+
+	configFile, err := filepath.Abs(path.Join(TargetDirectory, filename))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "filepath.Abs")
+	}
+
+	err = os.WriteFile(
+		configFile,
+		[]byte(format.String()),
+		0755,
+	)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "os.WriteFile")
+	}
+
+	for _, c := range cfg.Corpora {
+		corpusFiles, rejected, err := formatCorpus(ctx, c, configFile)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "formatting %s", c.Name)
+		}
+		if rejected != nil {
+			return nil, rejected, nil
+		}
+
+		files = append(files, corpusFiles...)
+	}
+
+	return files, nil, nil
+}
+
+// formatCorpus runs clang-format with the config file on the corpus and
+// collects every file it changed together with its committed version. If
+// clang-format rejects the config, the evaluation saying so is returned
+// instead.
+func formatCorpus(ctx context.Context, c Corpus, configFile string) ([]FormattedFile, *Evaluation, error) {
+	var stdErr strings.Builder
+	var stdOut strings.Builder
+
+	CFCtx, CFCxl := context.WithTimeout(
+		ctx,
+		10*time.Second,
+	)
+	defer CFCxl()
+
+	clangFormatCmd := exec.CommandContext(CFCtx,
+		"clang-format",
+		"-i",
+		"--style=file:"+configFile, // the corpus' own .clang-format must not win
+		"--verbose",
+		"--files="+c.FilesList,
+	)
+
+	clangFormatCmd.Stderr = &stdErr
+	// clangFormatCmd does not need stdOut
+
+	slog.Debug("running clang-format", "corpus", c.Name)
+	err := clangFormatCmd.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && CFCtx.Err() == nil {
+		slog.Debug("clang-format rejected the candidate", "exit_code", exitErr.ExitCode())
+
+		return nil, &Evaluation{
+			ExitCode: exitErr.ExitCode(),
+			Error:    strings.TrimSpace(stdErr.String()),
+		}, nil
+	}
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "clangFormatCmd.Run(): %s", stdErr.String())
+	}
+
+	// let's get the diff
+	diffCtx, diffCxl := context.WithTimeout(ctx, 10*time.Second)
+	defer diffCxl()
+
+	// Let's reset both writers, even though stdOut was not used above, probably
+	stdOut.Reset()
+	stdErr.Reset()
+
+	diffCmd := exec.CommandContext(diffCtx,
+		"git",
+		"--no-pager",
+		"diff",
+		"--numstat",
+		"-z",
+	)
+	diffCmd.Dir = c.Dir
+	diffCmd.Stdout = &stdOut
+	diffCmd.Stderr = &stdErr
+
+	slog.Debug("getting diff", "corpus", c.Name)
+	err = diffCmd.Run()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "diff: %s", stdErr.String())
+	}
+
+	entries, err := diff.ParseNumStatZ([]byte(stdOut.String()))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "diff.ParseNumStatZ")
+	}
+
+	if len(entries) == 0 {
+		slog.Debug("no lines changed", "corpus", c.Name)
+	}
+
+	files := make([]FormattedFile, 0, len(entries))
+	for _, e := range entries {
+		if e.Binary {
+			slog.Debug("skipping binary file", "file", e.Path)
+			continue
+		}
+
+		original, err := c.originals.get(ctx, e.Path)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "originals.get")
+		}
+
+		formatted, err := os.ReadFile(filepath.Join(c.Dir, e.Path))
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "os.ReadFile formatted")
+		}
+
+		files = append(files, FormattedFile{
+			Corpus:    c.Name,
+			Path:      e.Path,
+			Original:  original,
+			Formatted: formatted,
+		})
+	}
+
+	return files, nil, nil
+}
+
+// FileDiff is how the output of two configs differs for one file.
+type FileDiff struct {
+	Corpus string
+	Path   string
+	Stat   diff.NumStat
+	Hunks  []diff.Hunk
+}
+
+// Unified renders the difference like diff -u, a being the old side.
+func (d FileDiff) Unified() string {
+	return diff.Unified("a/"+d.Path, "b/"+d.Path, d.Hunks)
+}
+
+// Size is the number of lines added and removed.
+func (d FileDiff) Size() int {
+	return d.Stat.Added + d.Stat.Removed
+}
+
+// LargestHunks returns up to n hunks, the ones that add and remove the most
+// lines first.
+func (d FileDiff) LargestHunks(n int) []diff.Hunk {
+	hunks := slices.Clone(d.Hunks)
+	slices.SortStableFunc(hunks, func(a, b diff.Hunk) int {
+		return cmp.Compare(hunkSize(b), hunkSize(a))
+	})
+
+	return hunks[:max(0, min(n, len(hunks)))]
+}
+
+func hunkSize(h diff.Hunk) int {
+	n := 0
+	for _, l := range h.Lines {
+		if l.Op != diff.Equal {
+			n++
+		}
+	}
+
+	return n
+}
+
+// DiffFormatted compares what two configs made of the corpora. A file only
+// one of them changed is compared with its original on the other side. The
+// result is ordered by corpus and path, files both formatted the same way are
+// left out.
+func DiffFormatted(a, b []FormattedFile) []FileDiff {
+	type key struct{ corpus, path string }
+
+	original := make(map[key][]byte)
+	left := make(map[key][]byte)
+	right := make(map[key][]byte)
+	for _, f := range a {
+		original[key{f.Corpus, f.Path}] = f.Original
+		left[key{f.Corpus, f.Path}] = f.Formatted
+	}
+	for _, f := range b {
+		original[key{f.Corpus, f.Path}] = f.Original
+		right[key{f.Corpus, f.Path}] = f.Formatted
+	}
+
+	keys := make([]key, 0, len(original))
+	for k := range original {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(x, y key) int {
+		if c := strings.Compare(x.corpus, y.corpus); c != 0 {
+			return c
+		}
+		return strings.Compare(x.path, y.path)
+	})
+
+	diffs := make([]FileDiff, 0)
+	for _, k := range keys {
+		l, ok := left[k]
+		if !ok {
+			l = original[k]
+		}
+		r, ok := right[k]
+		if !ok {
+			r = original[k]
+		}
+
+		lines := diff.Lines(l, r)
+		stat := diff.Stat(lines)
+		if stat.Added+stat.Removed == 0 {
+			continue
+		}
+
+		diffs = append(diffs, FileDiff{
+			Corpus: k.corpus,
+			Path:   k.path,
+			Stat:   stat,
+			Hunks:  diff.Hunks(lines, diff.DefaultContext),
+		})
+	}
+
+	return diffs
+}
+
+// outputHash identifies what a candidate made of the corpora. Files it left
+// alone are not part of it, they are the same for every candidate.
+func outputHash(files []FormattedFile) string {
+	sorted := slices.Clone(files)
+	slices.SortFunc(sorted, func(a, b FormattedFile) int {
+		if c := strings.Compare(a.Corpus, b.Corpus); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	h := sha256.New()
+	for _, f := range sorted {
+		// the lengths keep names and contents from running into each other
+		fmt.Fprintf(h, "%d:%s%d:%s%d:", len(f.Corpus), f.Corpus, len(f.Path), f.Path, len(f.Formatted))
+		h.Write(f.Formatted)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,236 @@
+package clang_format
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+	"github.com/pkg/errors"
+)
+
+// Hash identifies the config: two configs with the same hash set every
+// option to the same value.
+func (c ClangFormat) Hash() string {
+	sum := sha256.Sum256([]byte(c.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// HistoryRecord is one line of the history: an evaluation and the config it
+// was made with.
+type HistoryRecord struct {
+	Time time.Time `json:"time"`
+
+	// Run tells apart the runs that appended to the same history.
+	Run string `json:"run"`
+
+	// Metric names what the costs were measured with, Weights and Corpora
+	// the file weights and the corpora, as name=weight, they were scored
+	// with. Costs scored differently don't compare.
+	Metric  string             `json:"metric"`
+	Weights metric.FileWeights `json:"weights,omitempty"`
+	Corpora []string           `json:"corpora,omitempty"`
+
+	ConfigHash string      `json:"config_hash"`
+	Format     ClangFormat `json:"format"`
+
+	Evaluation
+}
+
+// SameScoring reports whether the costs of r and o compare: they were
+// measured with the same metric, file weights and corpora.
+func (r HistoryRecord) SameScoring(o HistoryRecord) bool {
+	return r.Metric == o.Metric && slices.Equal(r.Weights, o.Weights) && slices.Equal(r.Corpora, o.Corpora)
+}
+
+// History appends every evaluation to w as one JSON object per line, so that
+// what a run found survives it, crashed or not.
+type History struct {
+	mu      sync.Mutex
+	w       io.Writer
+	run     string
+	weights metric.FileWeights
+	corpora []string
+	err     error
+}
+
+// NewHistory returns a history appending to w, its records marked with run
+// and the file weights and corpora of cfg.
+func NewHistory(w io.Writer, run string, cfg Config) *History {
+	corpora := make([]string, 0, len(cfg.Corpora))
+	for _, c := range cfg.Corpora {
+		corpora = append(corpora, fmt.Sprintf("%s=%g", c.Name, c.Weight))
+	}
+
+	return &History{w: w, run: run, weights: cfg.Weights, corpora: corpora}
+}
+
+// Record appends an evaluation made with format. A nil History records
+// nothing. Once a write failed, further records are dropped and the error is
+// available from Err.
+func (h *History) Record(metric string, format ClangFormat, e Evaluation) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.err != nil {
+		return
+	}
+
+	line, err := json.Marshal(HistoryRecord{
+		Time:       time.Now().UTC(),
+		Run:        h.run,
+		Metric:     metric,
+		Weights:    h.weights,
+		Corpora:    h.corpora,
+		ConfigHash: format.Hash(),
+		Format:     format,
+		Evaluation: e,
+	})
+	if err != nil {
+		h.err = errors.Wrap(err, "json.Marshal")
+		return
+	}
+
+	_, err = h.w.Write(append(line, '\n'))
+	if err != nil {
+		h.err = errors.Wrap(err, "writing history")
+	}
+}
+
+// Err returns the first error encountered while recording.
+func (h *History) Err() error {
+	if h == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.err
+}
+
+// ReadHistory reads the records of a history. A last line that is cut short,
+// as a run killed while writing leaves it, is skipped.
+func ReadHistory(r io.Reader) ([]HistoryRecord, error) {
+	records := make([]HistoryRecord, 0)
+
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, errors.Wrap(err, "reading history")
+		}
+
+		complete := err == nil
+		if len(bytes.TrimSpace(line)) > 0 {
+			rec := HistoryRecord{}
+			decodeErr := json.Unmarshal(line, &rec)
+			switch {
+			case decodeErr != nil && complete:
+				return nil, errors.Wrapf(decodeErr, "line %d", n)
+			case decodeErr == nil:
+				records = append(records, rec)
+			}
+		}
+
+		if !complete {
+			return records, nil
+		}
+	}
+}
+
+// LoadHistory reads the history in file.
+func LoadHistory(file string) ([]HistoryRecord, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "os.Open")
+	}
+	defer f.Close()
+
+	records, err := ReadHistory(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", file)
+	}
+
+	return records, nil
+}
+
+// BestConfigs returns the n cheapest configs evaluated with the metric, each
+// config once with its first evaluation, cheapest first. Only evaluations
+// scored like the latest one with the metric take part, as costs of other
+// file weights or corpora don't compare. Evaluations clang-format rejected
+// are left out.
+func BestConfigs(records []HistoryRecord, metric string, n int) []HistoryRecord {
+	var latest *HistoryRecord
+	for i := len(records) - 1; i >= 0 && latest == nil; i-- {
+		if records[i].Metric == metric {
+			latest = &records[i]
+		}
+	}
+
+	seen := make(map[string]bool)
+	best := make([]HistoryRecord, 0)
+	for _, r := range records {
+		if latest == nil || !r.SameScoring(*latest) || r.Failed() || seen[r.ConfigHash] {
+			continue
+		}
+
+		seen[r.ConfigHash] = true
+		best = append(best, r)
+	}
+
+	slices.SortStableFunc(best, func(a, b HistoryRecord) int {
+		return cmp.Compare(a.Total.Weighted, b.Total.Weighted)
+	})
+
+	return best[:min(n, len(best))]
+}
+
+// OptionResults returns every evaluation that tried a value of option, in the
+// order they were made.
+func OptionResults(records []HistoryRecord, option string) []HistoryRecord {
+	out := make([]HistoryRecord, 0)
+	for _, r := range records {
+		if r.Option == option {
+			out = append(out, r)
+		}
+	}
+
+	return out
+}
+
+// FindConfig returns the latest record of the config whose hash starts with
+// prefix. It is an error if no config, or more than one, matches.
+func FindConfig(records []HistoryRecord, prefix string) (HistoryRecord, error) {
+	found := make(map[string]HistoryRecord)
+	for _, r := range records {
+		if strings.HasPrefix(r.ConfigHash, prefix) {
+			found[r.ConfigHash] = r
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return HistoryRecord{}, errors.Errorf("no config with hash %s in the history", prefix)
+	case 1:
+		for _, r := range found {
+			return r, nil
+		}
+	}
+
+	return HistoryRecord{}, errors.Errorf("%d configs have a hash starting with %s", len(found), prefix)
+}
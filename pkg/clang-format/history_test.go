@@ -0,0 +1,106 @@
+package clang_format
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+)
+
+func TestHistory_RecordRead(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHistory(&buf, "run-1", Config{
+		Corpora: []Corpus{DefaultCorpus()},
+		Weights: metric.FileWeights{{Glob: "src/test/**", Weight: 0}},
+	})
+
+	format := ClangFormat{"IndentWidth": "4", "UseTab": "Never"}
+	h.Record("lines", format, Evaluation{
+		Pass:   1,
+		Option: "IndentWidth",
+		Value:  "4",
+		Total:  metric.Total{Raw: 12, Weighted: 12},
+		Files:  []FileResult{{Path: "src/a.c", Added: 3, Removed: 2, Changed: 3, Cost: 12, Weight: 1}},
+	})
+	if err := h.Err(); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	// a run killed half way through a line
+	buf.WriteString(`{"time":"2024-11-05T10:00:00Z","run":"run-1","met`)
+
+	got, err := ReadHistory(&buf)
+	if err != nil {
+		t.Fatalf("ReadHistory() error = %v", err)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("ReadHistory() read %d records, want 1", len(got))
+	}
+
+	r := got[0]
+	if r.Run != "run-1" || r.Metric != "lines" || r.ConfigHash != format.Hash() || r.Format["UseTab"] != "Never" ||
+		r.Option != "IndentWidth" || r.Total.Weighted != 12 || len(r.Files) != 1 || r.Files[0].Added != 3 ||
+		len(r.Weights) != 1 || r.Weights[0].Glob != "src/test/**" || len(r.Corpora) != 1 || r.Corpora[0] != "unit=1" {
+		t.Errorf("ReadHistory() = %+v", r)
+	}
+}
+
+func TestReadHistory_Corrupt(t *testing.T) {
+	_, err := ReadHistory(bytes.NewBufferString("{\"run\":\"a\"}\nnot json\n{\"run\":\"b\"}\n"))
+	if err == nil {
+		t.Errorf("ReadHistory() read a corrupt line in the middle without an error")
+	}
+}
+
+func TestHistoryQueries(t *testing.T) {
+	record := func(hash, metricName, option string, cost, exitCode int) HistoryRecord {
+		return HistoryRecord{
+			Metric:     metricName,
+			ConfigHash: hash,
+			Evaluation: Evaluation{Option: option, Total: metric.Total{Weighted: cost}, ExitCode: exitCode},
+		}
+	}
+
+	records := []HistoryRecord{
+		record("aaa1", "lines", "IndentWidth", 30, 0),
+		record("bbb1", "lines", "UseTab", 10, 0),
+		record("ccc1", "hunks", "UseTab", 5, 0),
+		record("ddd1", "lines", "UseTab", 0, 1),
+		record("aaa1", "lines", "IndentWidth", 30, 0),
+		record("aaa2", "lines", "IndentWidth", 20, 0),
+	}
+
+	// costs of other corpora, or of none recorded, don't compare with
+	// those of the latest evaluation
+	other := record("eee1", "lines", "UseTab", 1, 0)
+	other.Corpora = []string{"unit=1", "nginx=2"}
+	if best := BestConfigs(append(slices.Clone(records), other), "lines", 10); len(best) != 1 ||
+		best[0].ConfigHash != "eee1" {
+		t.Errorf("BestConfigs() = %+v, want only eee1, scored like the latest evaluation", best)
+	}
+
+	best := BestConfigs(records, "lines", 2)
+	if len(best) != 2 || best[0].ConfigHash != "bbb1" || best[1].ConfigHash != "aaa2" {
+		t.Errorf("BestConfigs() = %+v, want bbb1 and aaa2", best)
+	}
+
+	if all := BestConfigs(records, "lines", 10); len(all) != 3 {
+		t.Errorf("BestConfigs() returned %d configs, want the 3 distinct accepted ones", len(all))
+	}
+
+	if got := OptionResults(records, "UseTab"); len(got) != 3 {
+		t.Errorf("OptionResults() returned %d records, want 3", len(got))
+	}
+
+	if r, err := FindConfig(records, "bb"); err != nil || r.ConfigHash != "bbb1" {
+		t.Errorf("FindConfig(bb) = %+v, %v, want bbb1", r, err)
+	}
+	if _, err := FindConfig(records, "aaa"); err == nil {
+		t.Errorf("FindConfig(aaa) matched two configs without an error")
+	}
+	if _, err := FindConfig(records, "x"); err == nil {
+		t.Errorf("FindConfig(x) found a config that isn't there")
+	}
+}
@@ -0,0 +1,61 @@
+package clang_format
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// originals caches the committed content of corpus files, which is what the
+// formatted files are compared against. The corpus is reset to HEAD after
+// every evaluation, so HEAD is the original.
+type originals struct {
+	mu    sync.Mutex
+	dir   string
+	files map[string][]byte
+}
+
+func newOriginals(dir string) *originals {
+	return &originals{
+		dir:   dir,
+		files: make(map[string][]byte),
+	}
+}
+
+// get returns the content of file, relative to the corpus root, at HEAD.
+func (o *originals) get(ctx context.Context, file string) ([]byte, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if content, ok := o.files[file]; ok {
+		return content, nil
+	}
+
+	showCtx, showCxl := context.WithTimeout(ctx, 10*time.Second)
+	defer showCxl()
+
+	var stdOut, stdErr bytes.Buffer
+
+	showCmd := exec.CommandContext(showCtx,
+		"git",
+		"--no-pager",
+		"show",
+		"HEAD:"+file,
+	)
+	showCmd.Dir = o.dir
+	showCmd.Stdout = &stdOut
+	showCmd.Stderr = &stdErr
+
+	err := showCmd.Run()
+	if err != nil {
+		return nil, errors.Wrapf(err, "git show HEAD:%s: %s", file, stdErr.String())
+	}
+
+	o.files[file] = stdOut.Bytes()
+
+	return o.files[file], nil
+}
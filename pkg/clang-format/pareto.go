@@ -0,0 +1,147 @@
+package clang_format
+
+import (
+	"slices"
+
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+)
+
+// Objective is a score besides the cost a candidate is judged on, lower is
+// better.
+type Objective interface {
+	Name() string
+	Score(format ClangFormat, files []*metric.File) int
+}
+
+// MetricObjective scores the changes a candidate made with another metric.
+type MetricObjective struct {
+	Metric  metric.Metric
+	Weights metric.FileWeights
+}
+
+func (m MetricObjective) Name() string { return m.Metric.Name() }
+
+func (m MetricObjective) Score(_ ClangFormat, files []*metric.File) int {
+	return metric.Sum(m.Metric, m.Weights, files).Weighted
+}
+
+// Distance counts the options of a candidate whose value differs from a
+// reference style, an option the reference does not set counting as
+// different.
+type Distance struct {
+	// Reference has the value of every option of the style, as DumpConfig
+	// reports them. A .clang-format that only names its BasedOnStyle sets
+	// next to nothing itself, every candidate would be as far from it.
+	Reference ClangFormat
+}
+
+func (Distance) Name() string { return "distance" }
+
+func (d Distance) Score(format ClangFormat, _ []*metric.File) int {
+	n := 0
+	for k, v := range format {
+		if ref, ok := d.Reference[k]; !ok || ref != v {
+			n++
+		}
+	}
+
+	return n
+}
+
+// ConfigSize counts the options a candidate has to spell out on top of a
+// base style: those whose value differs from the style's default.
+type ConfigSize struct {
+	// Defaults are the values of the base style, as DumpConfig reports them.
+	Defaults ClangFormat
+}
+
+func (ConfigSize) Name() string { return "size" }
+
+func (s ConfigSize) Score(format ClangFormat, files []*metric.File) int {
+	return Distance{Reference: s.Defaults}.Score(format, files)
+}
+
+// ParetoPoint is a candidate no other candidate beats on every objective.
+type ParetoPoint struct {
+	// Scores are in the order of Run.Objectives, the cost first.
+	Scores []int       `json:"scores"`
+	Format ClangFormat `json:"format"`
+
+	// Pass, Option and Value say which option and value found the point,
+	// set on the search's config or on another point of the front.
+	Pass   int    `json:"pass"`
+	Option string `json:"option"`
+	Value  string `json:"value"`
+}
+
+// dominates reports whether a is at least as good as b on every score and
+// better on one.
+func dominates(a, b []int) bool {
+	better := false
+	for i := range a {
+		if a[i] > b[i] {
+			return false
+		}
+		if a[i] < b[i] {
+			better = true
+		}
+	}
+
+	return better
+}
+
+// addToFront adds p to the front unless a point on it dominates p, and drops
+// the points p dominates. A point with the same scores is replaced: the later
+// candidate is further along the search, closer to the final config.
+func addToFront(front []ParetoPoint, p ParetoPoint) []ParetoPoint {
+	for _, q := range front {
+		if dominates(q.Scores, p.Scores) {
+			return front
+		}
+	}
+
+	front = slices.DeleteFunc(front, func(q ParetoPoint) bool {
+		return dominates(p.Scores, q.Scores) || slices.Equal(q.Scores, p.Scores)
+	})
+
+	return append(front, p)
+}
+
+// sortFront orders the front by cost, then by the other scores.
+func sortFront(front []ParetoPoint) {
+	slices.SortFunc(front, func(a, b ParetoPoint) int {
+		return slices.Compare(a.Scores, b.Scores)
+	})
+}
+
+// thinFront sorts the front and picks n of its points, spread evenly along
+// it from the cheapest on, so that the search refines every kind of
+// trade-off rather than a cluster of them.
+func thinFront(front []ParetoPoint, n int) []ParetoPoint {
+	sortFront(front)
+	if len(front) <= n {
+		return front
+	}
+	if n <= 1 {
+		return front[:n]
+	}
+
+	kept := make([]ParetoPoint, 0, n)
+	for i := range n {
+		kept = append(kept, front[i*(len(front)-1)/(n-1)])
+	}
+
+	return kept
+}
+
+// objectiveScores scores a candidate on every objective, the cost first.
+func objectiveScores(cfg Config, format ClangFormat, files []*metric.File, total metric.Total) []int {
+	scores := make([]int, 0, len(cfg.Objectives)+1)
+	scores = append(scores, total.Weighted)
+
+	for _, o := range cfg.Objectives {
+		scores = append(scores, o.Score(format, files))
+	}
+
+	return scores
+}
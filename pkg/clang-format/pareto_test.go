@@ -0,0 +1,108 @@
+package clang_format
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func Test_addToFront(t *testing.T) {
+	point := func(value string, scores ...int) ParetoPoint {
+		return ParetoPoint{Value: value, Scores: scores}
+	}
+
+	tests := []struct {
+		name  string
+		front []ParetoPoint
+		add   ParetoPoint
+		want  []string
+	}{
+		{
+			name: "empty front",
+			add:  point("a", 10, 5),
+			want: []string{"a"},
+		},
+		{
+			name:  "trade-off is kept",
+			front: []ParetoPoint{point("a", 10, 5)},
+			add:   point("b", 12, 3),
+			want:  []string{"a", "b"},
+		},
+		{
+			name:  "dominated is rejected",
+			front: []ParetoPoint{point("a", 10, 5), point("b", 12, 3)},
+			add:   point("c", 11, 5),
+			want:  []string{"a", "b"},
+		},
+		{
+			name:  "same scores replace the older point",
+			front: []ParetoPoint{point("a", 10, 5)},
+			add:   point("b", 10, 5),
+			want:  []string{"b"},
+		},
+		{
+			name:  "dominating drops what it beats",
+			front: []ParetoPoint{point("a", 10, 5), point("b", 12, 3), point("c", 20, 1)},
+			add:   point("d", 10, 3),
+			want:  []string{"c", "d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, p := range addToFront(tt.front, tt.add) {
+				got = append(got, p.Value)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("addToFront() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_thinFront(t *testing.T) {
+	front := make([]ParetoPoint, 0)
+	for i, v := range []string{"f", "e", "d", "c", "b", "a"} {
+		front = append(front, ParetoPoint{Value: v, Scores: []int{10 - i, i}})
+	}
+
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{n: 10, want: []string{"a", "b", "c", "d", "e", "f"}},
+		{n: 3, want: []string{"a", "c", "f"}},
+		{n: 2, want: []string{"a", "f"}},
+		{n: 1, want: []string{"a"}},
+	}
+	for _, tt := range tests {
+		got := make([]string, 0)
+		for _, p := range thinFront(slices.Clone(front), tt.n) {
+			got = append(got, p.Value)
+		}
+
+		// the cheapest and the best on the other objective are always kept
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("thinFront(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	d := Distance{Reference: ClangFormat{"IndentWidth": "4", "UseTab": "Never", "Language": "Cpp"}}
+
+	got := d.Score(ClangFormat{"IndentWidth": "2", "UseTab": "Never", "TabWidth": "4"}, nil)
+	if got != 2 {
+		t.Errorf("Distance.Score() = %d, want 2", got)
+	}
+}
+
+func TestConfigSize(t *testing.T) {
+	s := ConfigSize{Defaults: ClangFormat{"IndentWidth": "2", "UseTab": "Never", "ColumnLimit": "80"}}
+
+	got := s.Score(ClangFormat{"IndentWidth": "4", "UseTab": "Never", "ColumnLimit": "80", "InsertBraces": "true"}, nil)
+	if got != 2 {
+		t.Errorf("ConfigSize.Score() = %d, want 2", got)
+	}
+}
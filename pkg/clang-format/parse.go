@@ -0,0 +1,137 @@
+package clang_format
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ParseClangFormat reads a .clang-format file into the flat form the tool
+// uses, nested options becoming "Group.Option". Values are kept as written,
+// quotes included, like the option catalog has them. It understands what String
+// writes and what clang-format --dump-config prints; list values, like
+// IncludeCategories, are skipped as the tool never sets them.
+func ParseClangFormat(raw []byte) (ClangFormat, error) {
+	format := make(ClangFormat)
+
+	group := ""
+	groupIndent := -1
+	inList := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimLeft(line, " ")
+		indent := len(line) - len(trimmed)
+
+		switch {
+		case trimmed == "", strings.HasPrefix(trimmed, "#"), trimmed == "---", trimmed == "...":
+			continue
+		case strings.HasPrefix(trimmed, "- ") || trimmed == "-":
+			// the group is a list value, skip it up to the next option
+			inList = true
+			continue
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return nil, errors.Errorf("line %d: %q is not key: value", n, line)
+		}
+		key = strings.TrimSpace(key)
+		value = stripComment(strings.TrimSpace(value))
+
+		if indent == 0 {
+			group, groupIndent, inList = "", -1, false
+			if value == "" {
+				// a group, or a list, follows on the next lines
+				group = key
+				continue
+			}
+
+			format[key] = value
+			continue
+		}
+
+		if inList {
+			continue
+		}
+
+		if group == "" {
+			return nil, errors.Errorf("line %d: %q is indented outside of a group", n, line)
+		}
+
+		if groupIndent < 0 {
+			groupIndent = indent
+		}
+
+		// deeper lines are nested further than the tool goes
+		if indent != groupIndent || value == "" {
+			continue
+		}
+
+		format[group+dot+key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "scanning")
+	}
+
+	return format, nil
+}
+
+// LoadClangFormat reads and parses the .clang-format file at file.
+func LoadClangFormat(file string) (ClangFormat, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "os.ReadFile")
+	}
+
+	format, err := ParseClangFormat(raw)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", file)
+	}
+
+	return format, nil
+}
+
+// DumpConfig returns the values clang-format gives every option of the named
+// base style, like LLVM or Google.
+func DumpConfig(ctx context.Context, style string) (ClangFormat, error) {
+	dumpCtx, dumpCxl := context.WithTimeout(ctx, 10*time.Second)
+	defer dumpCxl()
+
+	var stdErr strings.Builder
+
+	cmd := exec.CommandContext(dumpCtx, "clang-format", "--dump-config", "--style="+style)
+	cmd.Stderr = &stdErr
+
+	raw, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "clang-format --dump-config --style=%s: %s", style, stdErr.String())
+	}
+
+	format, err := ParseClangFormat(raw)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing the %s style", style)
+	}
+
+	return format, nil
+}
+
+func stripComment(value string) string {
+	if strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'") {
+		return value
+	}
+
+	if i := strings.Index(value, " #"); i >= 0 {
+		return strings.TrimSpace(value[:i])
+	}
+
+	return value
+}
@@ -0,0 +1,80 @@
+package clang_format
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseClangFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    ClangFormat
+		wantErr bool
+	}{
+		{
+			name: "dump-config style",
+			raw: `---
+Language:        Cpp
+# a comment
+AccessModifierOffset: -2
+AlignConsecutiveMacros:
+  Enabled:         false
+  AcrossComments:  true # trailing comment
+CommentPragmas:  '^ IWYU pragma:'
+ForEachMacros:
+  - foreach
+  - Q_FOREACH
+IncludeCategories:
+  - Regex:           '^"(llvm|llvm-c|clang|clang-c)/'
+    Priority:        2
+  - Regex:           '.*'
+    Priority:        1
+IndentWidth:     4
+...
+`,
+			want: ClangFormat{
+				"Language":                              "Cpp",
+				"AccessModifierOffset":                  "-2",
+				"AlignConsecutiveMacros.Enabled":        "false",
+				"AlignConsecutiveMacros.AcrossComments": "true",
+				"CommentPragmas":                        "'^ IWYU pragma:'",
+				"IndentWidth":                           "4",
+			},
+		},
+		{
+			name:    "not key value",
+			raw:     "IndentWidth 4\n",
+			wantErr: true,
+		},
+		{
+			name:    "indented without a group",
+			raw:     "IndentWidth: 4\n  Enabled: true\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseClangFormat([]byte(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseClangFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseClangFormat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseClangFormat_roundTrip(t *testing.T) {
+	want := generateBasic(options)
+
+	got, err := ParseClangFormat([]byte(want.String()))
+	if err != nil {
+		t.Fatalf("ParseClangFormat() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseClangFormat(String()) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,205 @@
+package clang_format
+
+import (
+	"maps"
+
+	"github.com/javorszky/go-diff-clang/pkg/diff"
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+	"github.com/pkg/errors"
+)
+
+// RescoreConfig is how Rescore replays a run.
+type RescoreConfig struct {
+	// Metric scores the recorded diffs again. Without one the recorded
+	// per-file costs stand.
+	Metric metric.Metric
+
+	// Weights multiply the cost of the files they match. Without any, every
+	// file keeps the weight it was recorded with. With neither Metric nor
+	// Weights the recorded costs stand and only the tie-break changes.
+	Weights metric.FileWeights
+
+	// TieBreak picks among values that cost the same. It defaults to
+	// TieBreakFirst.
+	TieBreak TieBreak
+}
+
+// RescoredDecision is a recorded decision made again.
+type RescoredDecision struct {
+	Pass   int    `json:"pass"`
+	Option string `json:"option"`
+
+	// Recorded is the value the run picked, Value the one the replay picks.
+	Recorded string `json:"recorded"`
+	Value    string `json:"value"`
+
+	// Costs has the cost of every value the run scored, as the replay
+	// scores it.
+	Costs map[string]int `json:"costs"`
+}
+
+// Changed reports whether the replay picked another value than the run.
+func (d RescoredDecision) Changed() bool {
+	return d.Value != d.Recorded
+}
+
+// RescoreResult is a run replayed.
+type RescoreResult struct {
+	Metric   string   `json:"metric"`
+	TieBreak TieBreak `json:"tie_break"`
+
+	// Decisions are the decisions the recorded evaluations could be used
+	// for, in the order the run made them.
+	Decisions []RescoredDecision `json:"decisions"`
+
+	// Format is the config the replay arrived at. It is the result of the
+	// search with the new metric only when nothing is Pending.
+	Format ClangFormat `json:"format"`
+
+	// Pending are the decisions, from the first one whose evaluations were
+	// made on a config the replay doesn't have, that need fresh evaluations.
+	// Differs has the options the replay had set differently at that point,
+	// with the replay's values.
+	Pending []Decision        `json:"pending,omitempty"`
+	Differs map[string]string `json:"differs,omitempty"`
+}
+
+// Rescore replays the decisions of a run with another metric or tie-break,
+// using the evaluations recorded in the run instead of running clang-format.
+// Once the replay picks a value the run did not, the evaluations of later
+// decisions were made on another config than the replay's, and only those
+// of a decision on the option that differs can still be used. The replay
+// stops at the first decision it can't make and lists it and the rest as
+// Pending.
+func Rescore(run *Run, cfg RescoreConfig) (*RescoreResult, error) {
+	if cfg.TieBreak == "" {
+		cfg.TieBreak = TieBreakFirst
+	}
+
+	result := &RescoreResult{
+		Metric:    run.Metric,
+		TieBreak:  cfg.TieBreak,
+		Decisions: make([]RescoredDecision, 0, len(run.Decisions)),
+	}
+	if cfg.Metric != nil {
+		result.Metric = cfg.Metric.Name()
+	}
+
+	evaluations := make(map[evaluationKey]Evaluation, len(run.Evaluations))
+	for _, e := range run.Evaluations {
+		evaluations[evaluationKey{e.Pass, e.Option, e.Value}] = e
+	}
+
+	// Every search starts from the same config, the two only drift apart
+	// where the replay decides differently.
+	recorded := generateBasic(options)
+	replayed := maps.Clone(recorded)
+
+	for i, d := range run.Decisions {
+		differs := differences(replayed, recorded, d.Option)
+		if len(differs) > 0 {
+			result.Pending = run.Decisions[i:]
+			result.Differs = differs
+			break
+		}
+
+		r := RescoredDecision{
+			Pass:     d.Pass,
+			Option:   d.Option,
+			Recorded: d.Value,
+			Costs:    d.Costs,
+		}
+
+		if cfg.Metric != nil || len(cfg.Weights) > 0 {
+			r.Costs = make(map[string]int, len(d.Costs))
+			for value := range d.Costs {
+				e, ok := evaluations[evaluationKey{d.Pass, d.Option, value}]
+				if !ok {
+					return nil, errors.Errorf("pass %d has no evaluation of %s: %s", d.Pass, d.Option, value)
+				}
+
+				cost, err := rescoreEvaluation(e, cfg)
+				if err != nil {
+					return nil, errors.Wrapf(err, "pass %d, %s: %s", d.Pass, d.Option, value)
+				}
+
+				r.Costs[value] = cost
+			}
+		}
+
+		r.Value, _ = cfg.TieBreak.winner(passCatalog(d.Pass)[d.Option], r.Costs, replayed[d.Option])
+		result.Decisions = append(result.Decisions, r)
+
+		recorded[d.Option] = d.Value
+		replayed[d.Option] = r.Value
+	}
+
+	result.Format = replayed
+
+	return result, nil
+}
+
+// evaluationKey finds the evaluation of a value in a pass.
+type evaluationKey struct {
+	pass          int
+	option, value string
+}
+
+// differences returns the options other than option that replayed sets
+// differently from recorded, with the values of replayed.
+func differences(replayed, recorded ClangFormat, option string) map[string]string {
+	out := make(map[string]string)
+	for k, v := range replayed {
+		if k != option && recorded[k] != v {
+			out[k] = v
+		}
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+
+	return out
+}
+
+// rescoreEvaluation scores the recorded diffs of an evaluation with the
+// metric of cfg, or reweighs the recorded per-file costs when cfg has no
+// metric, combining corpora the way the run did.
+func rescoreEvaluation(e Evaluation, cfg RescoreConfig) (int, error) {
+	costs := make(map[string][]metric.Cost)
+	for _, fr := range e.Files {
+		weight := fr.Weight
+		if len(cfg.Weights) > 0 {
+			weight = cfg.Weights.Weight(fr.Path)
+		}
+
+		raw := fr.Cost
+		if cfg.Metric != nil {
+			if fr.Diff == "" && fr.Added+fr.Removed > 0 {
+				return 0, errors.Errorf("%s has no recorded diff, the run was made without recording diffs",
+					fr.Path)
+			}
+
+			hunks, err := diff.ParseUnified(fr.Diff)
+			if err != nil {
+				return 0, errors.Wrapf(err, "diff of %s", fr.Path)
+			}
+
+			raw = cfg.Metric.FileCost(metric.FromHunks(fr.Path, hunks))
+		}
+
+		costs[fr.Corpus] = append(costs[fr.Corpus], metric.Cost{Path: fr.Path, Raw: raw, Weighted: raw * weight})
+	}
+
+	if len(e.Corpora) == 0 {
+		return metric.SumCosts(costs[""]).Weighted, nil
+	}
+
+	corpora := make([]CorpusResult, len(e.Corpora))
+	for i, c := range e.Corpora {
+		c.Total = metric.SumCosts(costs[c.Name])
+		corpora[i] = c
+	}
+
+	return combineCorpora(corpora).Weighted, nil
+}
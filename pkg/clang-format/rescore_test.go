@@ -0,0 +1,139 @@
+package clang_format
+
+import (
+	"testing"
+
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+)
+
+func TestRescore(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         RescoreConfig
+		wantValues  []string
+		wantPending int
+		wantDiffers map[string]string
+		wantCosts   map[string]int
+	}{
+		{
+			name:        "tie-break alone diverges",
+			cfg:         RescoreConfig{TieBreak: TieBreakFirst},
+			wantValues:  []string{"Align"},
+			wantPending: 1,
+			wantDiffers: map[string]string{"AlignAfterOpenBracket": "Align"},
+			wantCosts:   map[string]int{"Align": 2, "DontAlign": 2},
+		},
+		{
+			name:       "keeping the value",
+			cfg:        RescoreConfig{TieBreak: TieBreakKeep},
+			wantValues: []string{"Align"},
+			// Align is what the basic config starts with, so keep picks it
+			// too and the replay diverges as well
+			wantPending: 1,
+			wantDiffers: map[string]string{"AlignAfterOpenBracket": "Align"},
+			wantCosts:   map[string]int{"Align": 2, "DontAlign": 2},
+		},
+		{
+			name:        "a new metric scores the diffs",
+			cfg:         RescoreConfig{Metric: metric.Hunks{}},
+			wantValues:  []string{"Align"},
+			wantPending: 1,
+			wantDiffers: map[string]string{"AlignAfterOpenBracket": "Align"},
+			wantCosts:   map[string]int{"Align": 1, "DontAlign": 2},
+		},
+		{
+			name:        "new weights reweigh the recorded costs",
+			cfg:         RescoreConfig{Weights: metric.FileWeights{{Glob: "src/**", Weight: 3}}},
+			wantValues:  []string{"Align"},
+			wantPending: 1,
+			wantDiffers: map[string]string{"AlignAfterOpenBracket": "Align"},
+			wantCosts:   map[string]int{"Align": 6, "DontAlign": 6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Rescore(recordedRun(), tt.cfg)
+			if err != nil {
+				t.Fatalf("Rescore() error = %v", err)
+			}
+
+			if len(got.Decisions) != len(tt.wantValues) {
+				t.Fatalf("Rescore() made %d decisions, want %d", len(got.Decisions), len(tt.wantValues))
+			}
+
+			for i, d := range got.Decisions {
+				if d.Value != tt.wantValues[i] {
+					t.Errorf("decision %d = %s, want %s", i, d.Value, tt.wantValues[i])
+				}
+			}
+
+			if len(got.Pending) != tt.wantPending {
+				t.Errorf("Rescore() left %d decisions pending, want %d", len(got.Pending), tt.wantPending)
+			}
+
+			for k, v := range tt.wantDiffers {
+				if got.Differs[k] != v {
+					t.Errorf("Differs[%s] = %q, want %q", k, got.Differs[k], v)
+				}
+			}
+
+			for k, v := range tt.wantCosts {
+				if got.Decisions[0].Costs[k] != v {
+					t.Errorf("Costs[%s] = %d, want %d", k, got.Decisions[0].Costs[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestRescore_followsTheRun(t *testing.T) {
+	run := recordedRun()
+	run.Decisions[0].Value = "Align"
+
+	got, err := Rescore(run, RescoreConfig{Metric: metric.Hunks{}})
+	if err != nil {
+		t.Fatalf("Rescore() error = %v", err)
+	}
+
+	if len(got.Pending) != 0 || len(got.Decisions) != 2 {
+		t.Fatalf("Rescore() = %+v, want both decisions replayed", got)
+	}
+
+	// true changes fewer lines, but in two hunks
+	d := got.Decisions[1]
+	if !d.Changed() || d.Value != "false" || got.Format["SpaceAfterCStyleCast"] != "false" {
+		t.Errorf("second decision = %+v, want false to win on hunks", d)
+	}
+}
+
+func TestRescore_noDiffs(t *testing.T) {
+	run := recordedRun()
+	run.Evaluations[0].Files[0].Diff = ""
+
+	_, err := Rescore(run, RescoreConfig{Metric: metric.Hunks{}})
+	if err == nil {
+		t.Errorf("Rescore() scored an evaluation without its diff")
+	}
+}
+
+func TestTieBreak_winner(t *testing.T) {
+	values := []string{"None", "Left", "Right"}
+	costs := map[string]int{"None": 5, "Left": 3, "Right": 3, "Center": 3}
+
+	if got, cost := TieBreakFirst.winner(values, costs, "Right"); got != "Left" || cost != 3 {
+		t.Errorf("first winner() = %s, %d, want Left, 3", got, cost)
+	}
+	if got, _ := TieBreakKeep.winner(values, costs, "Right"); got != "Right" {
+		t.Errorf("keep winner() = %s, want Right", got)
+	}
+	if got, _ := TieBreakKeep.winner(values, costs, "None"); got != "Left" {
+		t.Errorf("keep winner() = %s, want Left as None isn't among the cheapest", got)
+	}
+	if got, _ := TieBreakFirst.winner(nil, costs, ""); got != "Center" {
+		t.Errorf("winner() without a catalog order = %s, want Center, the first sorted", got)
+	}
+
+	if _, err := ParseTieBreak("random"); err == nil {
+		t.Errorf("ParseTieBreak(random) returned no error")
+	}
+}
@@ -0,0 +1,229 @@
+package clang_format
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+	"github.com/pkg/errors"
+)
+
+// FileResult is what one candidate did to one file of the corpus.
+type FileResult struct {
+	// Corpus names the corpus the file is in when there are several.
+	Corpus string `json:"corpus,omitempty"`
+
+	// Path is relative to the corpus root.
+	Path    string `json:"path"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+
+	// Changed is the larger of Added and Removed: 5 added and 4 removed
+	// lines are 4 changed lines and 1 added one.
+	Changed int `json:"changed"`
+
+	// Cost is what the metric made of the file, Weight what its cost was
+	// multiplied by.
+	Cost   float64 `json:"cost"`
+	Weight float64 `json:"weight"`
+
+	// Diff is the unified diff of the file, when the run recorded diffs.
+	Diff string `json:"diff,omitempty"`
+}
+
+// Evaluation is the outcome of formatting the corpus with one candidate.
+type Evaluation struct {
+	Pass   int    `json:"pass"`
+	Option string `json:"option"`
+	Value  string `json:"value"`
+
+	Total      metric.Total          `json:"total"`
+	Categories metric.CategoryCounts `json:"categories"`
+
+	// Corpora breaks Total down when there are several corpora, their
+	// costs not normalized.
+	Corpora []CorpusResult `json:"corpora,omitempty"`
+
+	// OutputHash identifies the formatted code: two candidates with the same
+	// hash formatted every file the same way.
+	OutputHash string `json:"output_hash,omitempty"`
+
+	// Scores holds the score on every objective, in the order of
+	// Run.Objectives, when the run had more than the cost to go by.
+	Scores []int `json:"scores,omitempty"`
+
+	// Files lists every file the candidate changed.
+	Files []FileResult `json:"files"`
+
+	DurationMS int64 `json:"duration_ms"`
+
+	// ExitCode is clang-format's exit status. When it is not 0 the candidate
+	// was not scored, and Error holds what clang-format complained about.
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Failed reports whether clang-format could not format the corpus with the
+// candidate.
+func (e Evaluation) Failed() bool {
+	return e.ExitCode != 0
+}
+
+// Run is everything a search found out: the best format and every evaluation
+// that led to it, in the order they were made.
+type Run struct {
+	Metric      string       `json:"metric"`
+	TieBreak    TieBreak     `json:"tie_break,omitempty"`
+	Format      ClangFormat  `json:"format"`
+	Total       metric.Total `json:"total"`
+	Evaluations []Evaluation `json:"evaluations"`
+
+	// Corpora is the cost of Format on every corpus when there are several.
+	Corpora []CorpusResult `json:"corpora,omitempty"`
+
+	// Decisions records how every option was settled in every pass, Passes
+	// where each pass left the cost.
+	Decisions []Decision   `json:"decisions"`
+	Passes    []PassResult `json:"passes"`
+
+	// Objectives names the scores of a multi-objective run, the metric
+	// first. Front is the set of evaluated candidates no other evaluated
+	// candidate beats on every objective, ordered by cost.
+	Objectives []string      `json:"objectives,omitempty"`
+	Front      []ParetoPoint `json:"front,omitempty"`
+
+	// tried holds the hashes of the configs evaluated so far, so that the
+	// front isn't refined with a config twice.
+	tried map[string]bool
+}
+
+// Decision is how a pass settled one option.
+type Decision struct {
+	Pass   int    `json:"pass"`
+	Option string `json:"option"`
+
+	// Value is the value that won.
+	Value string `json:"value"`
+
+	// Costs has the cost of every value clang-format accepted.
+	Costs map[string]int `json:"costs"`
+
+	// Irrelevant is set when every value cost the same. Identical is set
+	// when they formatted the code byte for byte the same way too, so the
+	// option really makes no difference to the corpus. An irrelevant option
+	// that is not identical is a style choice the cost can't make.
+	Irrelevant bool `json:"irrelevant"`
+	Identical  bool `json:"identical"`
+}
+
+// RunnerUp returns the cheapest value other than the winner, ties going to
+// the value that sorts first. It reports false if no other value was scored.
+func (d Decision) RunnerUp() (string, int, bool) {
+	best, bestCost, found := "", 0, false
+	for _, value := range slices.Sorted(maps.Keys(d.Costs)) {
+		if value == d.Value {
+			continue
+		}
+
+		if !found || d.Costs[value] < bestCost {
+			best, bestCost, found = value, d.Costs[value], true
+		}
+	}
+
+	return best, bestCost, found
+}
+
+// PassResult is where a pass left the search.
+type PassResult struct {
+	Pass  int          `json:"pass"`
+	Total metric.Total `json:"total"`
+
+	// Irrelevant lists the options whose values all cost the same.
+	Irrelevant []string `json:"irrelevant"`
+}
+
+// WriteIrrelevant writes the options whose values all cost the same in the
+// decisions, one per line, in two groups: those that formatted the code
+// identically whatever their value, and those that only tie on cost.
+func WriteIrrelevant(w io.Writer, decisions []Decision) error {
+	identical := make([]string, 0)
+	tied := make([]string, 0)
+	for _, d := range decisions {
+		switch {
+		case d.Identical:
+			identical = append(identical, d.Option)
+		case d.Irrelevant:
+			tied = append(tied, d.Option)
+		}
+	}
+
+	buf := bytes.Buffer{}
+	buf.WriteString("# Every value of these options formats the code exactly the same way.\n")
+	for _, o := range identical {
+		buf.WriteString(o + "\n")
+	}
+
+	buf.WriteString("\n# Every value of these options changes as much, but the code comes out differently:\n")
+	buf.WriteString("# the choice is one of taste.\n")
+	for _, o := range tied {
+		buf.WriteString(o + "\n")
+	}
+
+	_, err := w.Write(buf.Bytes())
+	if err != nil {
+		return errors.Wrap(err, "writing irrelevant options")
+	}
+
+	return nil
+}
+
+// FinalDecisions returns the last decision made about every option, ordered
+// by option name. Those are the ones that stand in Format.
+func (r *Run) FinalDecisions() []Decision {
+	last := make(map[string]Decision)
+	for _, d := range r.Decisions {
+		last[d.Option] = d
+	}
+
+	out := make([]Decision, 0, len(last))
+	for _, option := range slices.Sorted(maps.Keys(last)) {
+		out = append(out, last[option])
+	}
+
+	return out
+}
+
+// Save writes the run to file as JSON.
+func (r *Run) Save(file string) error {
+	raw, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "json.MarshalIndent")
+	}
+
+	err = os.WriteFile(file, append(raw, '\n'), 0644)
+	if err != nil {
+		return errors.Wrap(err, "os.WriteFile")
+	}
+
+	return nil
+}
+
+// LoadRun reads a run written by Save.
+func LoadRun(file string) (*Run, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "os.ReadFile")
+	}
+
+	r := &Run{}
+	err = json.Unmarshal(raw, r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decoding %s", file)
+	}
+
+	return r, nil
+}
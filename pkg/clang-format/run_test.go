@@ -0,0 +1,193 @@
+package clang_format
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/javorszky/go-diff-clang/pkg/diff"
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+)
+
+func TestRun_SaveLoad(t *testing.T) {
+	want := &Run{
+		Metric: "lines",
+		Format: ClangFormat{"IndentWidth": "4", "BraceWrapping.AfterFunction": "true"},
+		Total:  metric.Total{Raw: 12, Weighted: 6},
+		Evaluations: []Evaluation{
+			{
+				Pass:       1,
+				Option:     "IndentWidth",
+				Value:      "4",
+				Total:      metric.Total{Raw: 12, Weighted: 6},
+				Categories: metric.CategoryCounts{metric.CategoryIndentation: 3},
+				Files: []FileResult{
+					{Path: "src/a.c", Added: 5, Removed: 4, Changed: 5, Cost: 5, Weight: 1},
+					{Path: "src/b.c", Added: 7, Removed: 7, Changed: 7, Cost: 7, Weight: 0.14},
+				},
+				DurationMS: 1200,
+			},
+			{
+				Pass:     1,
+				Option:   "IndentWidth",
+				Value:    "2",
+				ExitCode: 1,
+				Error:    "invalid value",
+			},
+		},
+	}
+
+	file := filepath.Join(t.TempDir(), "run.json")
+	if err := want.Save(file); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	got, err := LoadRun(file)
+	if err != nil {
+		t.Fatalf("LoadRun() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadRun() = %+v, want %+v", got, want)
+	}
+
+	if got.Evaluations[0].Failed() || !got.Evaluations[1].Failed() {
+		t.Errorf("Failed() does not follow the exit code")
+	}
+}
+
+func TestRun_FinalDecisions(t *testing.T) {
+	r := &Run{Decisions: []Decision{
+		{Pass: 1, Option: "IndentWidth", Value: "2", Costs: map[string]int{"2": 10, "4": 12}},
+		{Pass: 1, Option: "AlignOperands", Value: "Align", Costs: map[string]int{"Align": 5, "DontAlign": 5}},
+		{Pass: 2, Option: "IndentWidth", Value: "4", Costs: map[string]int{"2": 9, "4": 8, "8": 9}},
+	}}
+
+	got := r.FinalDecisions()
+	if len(got) != 2 || got[0].Option != "AlignOperands" || got[1].Pass != 2 {
+		t.Fatalf("FinalDecisions() = %+v, want AlignOperands and IndentWidth from pass 2", got)
+	}
+
+	value, cost, ok := got[1].RunnerUp()
+	if !ok || value != "2" || cost != 9 {
+		t.Errorf("RunnerUp() = %q, %d, %v, want \"2\", 9, true", value, cost, ok)
+	}
+
+	if _, _, ok := (Decision{Value: "x", Costs: map[string]int{"x": 1}}).RunnerUp(); ok {
+		t.Errorf("RunnerUp() found a runner-up with a single value")
+	}
+}
+
+func TestWriteIrrelevant(t *testing.T) {
+	decisions := []Decision{
+		{Option: "AlignOperands", Irrelevant: true},
+		{Option: "IndentWidth"},
+		{Option: "UseTab", Irrelevant: true, Identical: true},
+	}
+
+	buf := bytes.Buffer{}
+	err := WriteIrrelevant(&buf, decisions)
+	if err != nil {
+		t.Fatalf("WriteIrrelevant() error = %v", err)
+	}
+
+	want := "# Every value of these options formats the code exactly the same way.\n" +
+		"UseTab\n" +
+		"\n# Every value of these options changes as much, but the code comes out differently:\n" +
+		"# the choice is one of taste.\n" +
+		"AlignOperands\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteIrrelevant() wrote\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRun_Annotated(t *testing.T) {
+	r := &Run{
+		Format: ClangFormat{
+			"AlignArrayOfStructures":      "Left",
+			"BraceWrapping.AfterFunction": "true",
+			"IndentWidth":                 "4",
+			"UseTab":                      "Never",
+		},
+		Evaluations: []Evaluation{
+			{Pass: 1, Option: "IndentWidth", Value: "8", ExitCode: 1},
+		},
+		Decisions: []Decision{
+			{Pass: 1, Option: "AlignArrayOfStructures", Value: "Right",
+				Costs: map[string]int{"None": 9, "Left": 8, "Right": 7}},
+			{Pass: 1, Option: "IndentWidth", Value: "4", Costs: map[string]int{"2": 30, "4": 20}},
+			{Pass: 2, Option: "AlignArrayOfStructures", Value: "Left",
+				Costs: map[string]int{"None": 18102, "Left": 17666, "Right": 17840}},
+			{Pass: 2, Option: "BraceWrapping.AfterFunction", Value: "true",
+				Costs: map[string]int{"true": 5, "false": 5}, Irrelevant: true, Identical: true},
+		},
+	}
+
+	got := r.Annotated()
+
+	for _, want := range []string{
+		"AlignArrayOfStructures: Left # Left=17666 None=18102 Right=17840\n",
+		"IndentWidth: 4 # 4=20 2=30 8=rejected\n",
+		"UseTab: Never\n",
+		"  AfterFunction: true # true=5 false=5 (doesn't matter: identical output)\n",
+	} {
+		if !bytes.Contains([]byte(got), []byte(want)) {
+			t.Errorf("Annotated() has no line %q in\n%s", want, got)
+		}
+	}
+
+	parsed, err := ParseClangFormat([]byte(got))
+	if err != nil {
+		t.Fatalf("ParseClangFormat() error = %v", err)
+	}
+	if !reflect.DeepEqual(parsed, r.Format) {
+		t.Errorf("ParseClangFormat(Annotated()) = %v, want %v", parsed, r.Format)
+	}
+}
+
+// recordedRun is a run of two decisions with the diffs of every evaluation,
+// for the tests that work on the results of a run.
+// In the first, DontAlign ties with Align on lines and needs two hunks where
+// Align needs one. In the second, true changes fewer lines than false but in
+// more hunks.
+func recordedRun() *Run {
+	evaluation := func(pass int, option, value, original, formatted string) Evaluation {
+		f := metric.NewFile("src/a.c", []byte(original), []byte(formatted))
+		cost := max(f.Stat.Added, f.Stat.Removed)
+
+		return Evaluation{
+			Pass:   pass,
+			Option: option,
+			Value:  value,
+			Total:  metric.Total{Raw: cost, Weighted: cost},
+			Files: []FileResult{{
+				Path:    f.Path,
+				Added:   f.Stat.Added,
+				Removed: f.Stat.Removed,
+				Changed: cost,
+				Cost:    float64(cost),
+				Weight:  1,
+				Diff:    diff.Unified("a/"+f.Path, "b/"+f.Path, f.Hunks),
+			}},
+		}
+	}
+
+	original := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+
+	return &Run{
+		Metric: "lines",
+		Evaluations: []Evaluation{
+			evaluation(1, "AlignAfterOpenBracket", "Align", original, "1\n2\nx\ny\n5\n6\n7\n8\n9\n10\n"),
+			evaluation(1, "AlignAfterOpenBracket", "DontAlign", original, "x\n2\n3\n4\n5\n6\n7\n8\n9\ny\n"),
+			evaluation(1, "SpaceAfterCStyleCast", "true", original, "x\n2\n3\n4\n5\n6\n7\n8\n9\ny\n"),
+			evaluation(1, "SpaceAfterCStyleCast", "false", original, "1\n2\nx\ny\nz\n6\n7\n8\n9\n10\n"),
+		},
+		Decisions: []Decision{
+			{Pass: 1, Option: "AlignAfterOpenBracket", Value: "DontAlign",
+				Costs: map[string]int{"Align": 2, "DontAlign": 2}},
+			{Pass: 1, Option: "SpaceAfterCStyleCast", Value: "true",
+				Costs: map[string]int{"true": 2, "false": 3}},
+		},
+	}
+}
@@ -0,0 +1,208 @@
+package clang_format
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+	"github.com/pkg/errors"
+)
+
+// OptionSensitivity is what deviating from the value a config chose for one
+// option costs, every other option staying as it is.
+type OptionSensitivity struct {
+	Option string `json:"option"`
+	Value  string `json:"value"`
+
+	// Costs has the cost of every value clang-format accepted, the chosen
+	// one included. Rejected lists the values it did not.
+	Costs    map[string]int `json:"costs"`
+	Rejected []string       `json:"rejected,omitempty"`
+
+	// Cheapest is how much more the cheapest alternative costs than the
+	// chosen value, Worst how much more the most expensive one does. A
+	// negative Cheapest means an alternative beats the chosen value.
+	Cheapest int `json:"cheapest"`
+	Worst    int `json:"worst"`
+
+	// Identical is set when every value formatted the code byte for byte
+	// the same way.
+	Identical bool `json:"identical"`
+}
+
+// Free reports whether the option can be set by taste: some alternative
+// costs no more than the chosen value, or there is no alternative at all.
+func (o OptionSensitivity) Free() bool {
+	return o.Cheapest <= 0
+}
+
+// SensitivityResult is the sensitivity of every option of a config.
+type SensitivityResult struct {
+	Metric string       `json:"metric"`
+	Total  metric.Total `json:"total"`
+
+	// Options are ranked by impact: the options whose cheapest alternative
+	// costs the most come first.
+	Options []OptionSensitivity `json:"options"`
+}
+
+// Sensitivity evaluates every alternative value of every option format sets,
+// one option at a time with the rest of format fixed, and ranks the options
+// by how much deviating from them costs. The alternatives are the values any
+// pass of the search tries, options the tool does not know other values of
+// are skipped.
+func Sensitivity(ctx context.Context, cfg Config, format ClangFormat) (*SensitivityResult, error) {
+	cfg = cfg.withDefaults()
+
+	err := countCorpora(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		err := removeConfig()
+		if err != nil {
+			slog.Warn("could not remove generated config", "error", err)
+		}
+	}()
+
+	format = maps.Clone(format)
+
+	base, err := runOption(ctx, cfg, format)
+	if err != nil {
+		return nil, errors.Wrap(err, "runOption")
+	}
+
+	cfg.History.Record(cfg.Metric.Name(), format, base)
+
+	if base.Failed() {
+		return nil, errors.Errorf("clang-format rejected the config with exit status %d: %s",
+			base.ExitCode, base.Error)
+	}
+
+	slog.Info("base config", "cost", base.Total.Weighted)
+
+	result := &SensitivityResult{
+		Metric:  cfg.Metric.Name(),
+		Total:   base.Total,
+		Options: make([]OptionSensitivity, 0),
+	}
+
+	for _, option := range sensitiveOptions(format) {
+		slog.Info("checking option", "option", option)
+
+		s, err := optionSensitivity(ctx, cfg, format, option, base)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Options = append(result.Options, s)
+	}
+
+	slices.SortStableFunc(result.Options, func(a, b OptionSensitivity) int {
+		return cmp.Or(cmp.Compare(b.Cheapest, a.Cheapest), cmp.Compare(b.Worst, a.Worst))
+	})
+
+	return result, nil
+}
+
+// sensitiveOptions returns the options of format the search knows more than
+// one value of in any pass, in name order.
+func sensitiveOptions(format ClangFormat) []string {
+	out := make([]string, 0, len(format))
+	for _, option := range slices.Sorted(maps.Keys(format)) {
+		if len(KnownValues(option)) < 2 {
+			slog.Debug("skipping option without alternatives", "option", option)
+			continue
+		}
+
+		out = append(out, option)
+	}
+
+	return out
+}
+
+// optionSensitivity tries every other value of option on top of format, which
+// it leaves as it found it.
+func optionSensitivity(ctx context.Context, cfg Config, format ClangFormat, option string,
+	base Evaluation) (OptionSensitivity, error) {
+	chosen := format[option]
+	defer func() { format[option] = chosen }()
+
+	s := OptionSensitivity{
+		Option:   option,
+		Value:    chosen,
+		Costs:    map[string]int{chosen: base.Total.Weighted},
+		Rejected: make([]string, 0),
+	}
+	hashes := map[string]bool{base.OutputHash: true}
+
+	for _, value := range KnownValues(option) {
+		if value == chosen {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return s, errors.Wrap(ctx.Err(), "interrupted")
+		}
+
+		format[option] = value
+
+		e, err := runOption(ctx, cfg, format)
+		if err != nil {
+			if ctx.Err() != nil {
+				return s, errors.Wrap(ctx.Err(), "interrupted")
+			}
+
+			return s, errors.Wrap(err, "runOption")
+		}
+
+		e.Option, e.Value = option, value
+		cfg.History.Record(cfg.Metric.Name(), format, e)
+
+		if e.Failed() {
+			slog.Warn("clang-format rejected value, skipping it",
+				"option", option,
+				"value", value,
+				"exit_code", e.ExitCode,
+				"error", e.Error,
+			)
+			s.Rejected = append(s.Rejected, value)
+			continue
+		}
+
+		s.Costs[value] = e.Total.Weighted
+		hashes[e.OutputHash] = true
+	}
+
+	s.Cheapest, s.Worst = marginalCosts(s.Costs, chosen)
+	s.Identical = len(hashes) == 1
+
+	return s, nil
+}
+
+// marginalCosts returns how much more the cheapest and the most expensive
+// value other than chosen cost than chosen does, 0 and 0 when there is no
+// other value.
+func marginalCosts(costs map[string]int, chosen string) (int, int) {
+	cheapest, worst, found := 0, 0, false
+	for value, cost := range costs {
+		if value == chosen {
+			continue
+		}
+
+		delta := cost - costs[chosen]
+		if !found {
+			cheapest, worst, found = delta, delta, true
+			continue
+		}
+
+		cheapest = min(cheapest, delta)
+		worst = max(worst, delta)
+	}
+
+	return cheapest, worst
+}
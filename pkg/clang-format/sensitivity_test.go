@@ -0,0 +1,75 @@
+package clang_format
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMarginalCosts(t *testing.T) {
+	tests := []struct {
+		name     string
+		costs    map[string]int
+		chosen   string
+		cheapest int
+		worst    int
+		wantFree bool
+	}{
+		{
+			name:     "load bearing",
+			costs:    map[string]int{"Left": 100, "Right": 140, "None": 120},
+			chosen:   "Left",
+			cheapest: 20,
+			worst:    40,
+		},
+		{
+			name:     "tie",
+			costs:    map[string]int{"true": 100, "false": 100},
+			chosen:   "true",
+			wantFree: true,
+		},
+		{
+			name:     "an alternative is better",
+			costs:    map[string]int{"2": 100, "4": 90, "8": 130},
+			chosen:   "2",
+			cheapest: -10,
+			worst:    30,
+			wantFree: true,
+		},
+		{
+			name:     "no alternative",
+			costs:    map[string]int{"true": 100},
+			chosen:   "true",
+			wantFree: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cheapest, worst := marginalCosts(tt.costs, tt.chosen)
+			if cheapest != tt.cheapest || worst != tt.worst {
+				t.Errorf("marginalCosts() = %d, %d, want %d, %d", cheapest, worst, tt.cheapest, tt.worst)
+			}
+
+			s := OptionSensitivity{Cheapest: cheapest, Worst: worst}
+			if s.Free() != tt.wantFree {
+				t.Errorf("Free() = %v, want %v", s.Free(), tt.wantFree)
+			}
+		})
+	}
+}
+
+func TestSensitiveOptions(t *testing.T) {
+	// IndentWidth is only tuned by the last pass, UseTab has a single value
+	// in every pass
+	format := ClangFormat{"AlignArrayOfStructures": "Left", "IndentWidth": "4", "UseTab": "Never"}
+
+	got := sensitiveOptions(format)
+	want := []string{"AlignArrayOfStructures", "IndentWidth"}
+	if !slices.Equal(got, want) {
+		t.Errorf("sensitiveOptions() = %v, want %v", got, want)
+	}
+
+	if values := KnownValues("IndentWidth"); !slices.Equal(values, []string{"4", "2"}) {
+		t.Errorf("KnownValues(IndentWidth) = %v, want [4 2]", values)
+	}
+}
@@ -0,0 +1,67 @@
+package clang_format
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// TieBreak decides between the values of an option that cost the same.
+type TieBreak string
+
+const (
+	// TieBreakFirst picks the value listed first in the option catalog.
+	TieBreakFirst TieBreak = "first"
+
+	// TieBreakKeep keeps the value the config had if it is one of the
+	// cheapest, so later passes don't flip options that tie, and picks the
+	// first one otherwise.
+	TieBreakKeep TieBreak = "keep"
+)
+
+// TieBreaks are the tie-break policies ParseTieBreak knows.
+var TieBreaks = []TieBreak{TieBreakFirst, TieBreakKeep}
+
+// ParseTieBreak returns the tie-break policy called name.
+func ParseTieBreak(name string) (TieBreak, error) {
+	t := TieBreak(strings.TrimSpace(name))
+	if !slices.Contains(TieBreaks, t) {
+		return "", errors.Errorf("unknown tie-break %q", name)
+	}
+
+	return t, nil
+}
+
+// winner returns the cheapest value in costs and its cost. values is the
+// catalog order of the option, values missing from it come after them in
+// sorted order. previous is the value the config had, if any.
+func (t TieBreak) winner(values []string, costs map[string]int, previous string) (string, int) {
+	order := make([]string, 0, len(costs))
+	for _, v := range values {
+		if _, ok := costs[v]; ok {
+			order = append(order, v)
+		}
+	}
+	for _, v := range slices.Sorted(maps.Keys(costs)) {
+		if !slices.Contains(order, v) {
+			order = append(order, v)
+		}
+	}
+
+	best, bestCost := "", 0
+	for i, v := range order {
+		if i == 0 || costs[v] < bestCost {
+			best, bestCost = v, costs[v]
+		}
+	}
+
+	if t == TieBreakKeep {
+		if cost, ok := costs[previous]; ok && cost == bestCost {
+			return previous, cost
+		}
+	}
+
+	return best, bestCost
+}
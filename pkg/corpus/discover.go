@@ -0,0 +1,341 @@
+package corpus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	gitIgnoreFile         = ".gitignore"
+	clangFormatIgnoreFile = ".clang-format-ignore"
+)
+
+// Reason describes why a file was left out of the corpus.
+type Reason string
+
+const (
+	ReasonGitIgnore         Reason = "ignored by .gitignore"
+	ReasonClangFormatIgnore Reason = "ignored by .clang-format-ignore"
+	ReasonNotIncluded       Reason = "not matched by an include glob"
+	ReasonExcluded          Reason = "matched by an exclude glob"
+	ReasonExtension         Reason = "extension not selected"
+	ReasonVendored          Reason = "linguist-vendored"
+	ReasonGenerated         Reason = "linguist-generated"
+	ReasonUntracked         Reason = "not tracked by git"
+)
+
+// ExtensionSets are the named groups of extensions that can be passed in
+// place of individual extensions.
+var ExtensionSets = map[string][]string{
+	"c":    {".c", ".h"},
+	"cpp":  {".cc", ".cpp", ".cxx", ".c++", ".hh", ".hpp", ".hxx", ".h++", ".h"},
+	"objc": {".m", ".mm", ".h"},
+}
+
+// DiscoverConfig controls which files Discover picks up.
+type DiscoverConfig struct {
+	// Root is the corpus directory, usually the checked out repository.
+	Root string
+
+	// Include globs are relative to Root. If empty, every file is a
+	// candidate.
+	Include []string
+
+	// Exclude globs are relative to Root and win over Include.
+	Exclude []string
+
+	// Extensions holds extensions (".c") or names of ExtensionSets ("c").
+	Extensions []string
+}
+
+// Discovery is the outcome of a Discover call.
+type Discovery struct {
+	// Files are the included files, joined with the corpus root so they can
+	// be handed to clang-format from the working directory.
+	Files []string
+
+	// Skipped lists the skipped files, relative to the corpus root, keyed by
+	// the reason they were skipped for.
+	Skipped map[Reason][]string
+}
+
+// Discover walks the corpus root and returns the source files that should be
+// formatted, together with the reasons the rest were skipped. In a git
+// repository only tracked files are formatted.
+func Discover(cfg DiscoverConfig) (*Discovery, error) {
+	extensions, err := resolveExtensions(cfg.Extensions)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolveExtensions")
+	}
+
+	d := &Discovery{
+		Skipped: make(map[Reason][]string),
+	}
+
+	gitRules := map[string]ignoreRules{}
+	cfRules := map[string]ignoreRules{}
+
+	candidates := make([]string, 0)
+
+	err = filepath.WalkDir(cfg.Root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(cfg.Root, p)
+		if err != nil {
+			return errors.Wrapf(err, "filepath.Rel %s", p)
+		}
+		rel = filepath.ToSlash(rel)
+
+		parent := path.Dir(rel)
+
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+
+			if rel != "." {
+				// clang-format matches its ignore patterns against file
+				// paths only, so they don't prune directories
+				if gitRules[parent].ignored(rel, true) {
+					return filepath.SkipDir
+				}
+			}
+
+			// Each directory inherits its parent's .gitignore rules and
+			// adds its own. Rules appended later take precedence.
+			own, err := loadIgnoreFile(filepath.Join(p, gitIgnoreFile), rel, parseIgnoreLine)
+			if err != nil {
+				return err
+			}
+			gitRules[rel] = append(slices.Clip(gitRules[parent]), own...)
+
+			// clang-format only reads the nearest .clang-format-ignore, one
+			// lower down replaces those above it, even when it is empty.
+			cfFile := filepath.Join(p, clangFormatIgnoreFile)
+			own, err = loadIgnoreFile(cfFile, rel, parseClangFormatIgnoreLine)
+			if err != nil {
+				return err
+			}
+			cfRules[rel] = cfRules[parent]
+			if _, err := os.Stat(cfFile); err == nil {
+				cfRules[rel] = own
+			}
+
+			return nil
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		switch {
+		case gitRules[parent].ignored(rel, false):
+			d.skip(ReasonGitIgnore, rel)
+		case cfRules[parent].ignored(rel, false):
+			d.skip(ReasonClangFormatIgnore, rel)
+		case len(cfg.Include) > 0 && !matchAny(cfg.Include, rel):
+			d.skip(ReasonNotIncluded, rel)
+		case matchAny(cfg.Exclude, rel):
+			d.skip(ReasonExcluded, rel)
+		case len(extensions) > 0 && !slices.Contains(extensions, path.Ext(rel)):
+			d.skip(ReasonExtension, rel)
+		default:
+			candidates = append(candidates, rel)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "filepath.WalkDir %s", cfg.Root)
+	}
+
+	attrs, err := linguistAttributes(cfg.Root, candidates)
+	if err != nil {
+		return nil, errors.Wrap(err, "linguistAttributes")
+	}
+
+	// a reset doesn't restore what clang-format does to an untracked file
+	var tracked map[string]bool
+	if _, err := os.Stat(filepath.Join(cfg.Root, ".git")); err == nil {
+		tracked, err = trackedFiles(context.Background(), cfg.Root)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, rel := range candidates {
+		switch {
+		case tracked != nil && !tracked[rel]:
+			d.skip(ReasonUntracked, rel)
+		case attrs[rel][attrVendored]:
+			d.skip(ReasonVendored, rel)
+		case attrs[rel][attrGenerated]:
+			d.skip(ReasonGenerated, rel)
+		default:
+			d.Files = append(d.Files, filepath.Join(cfg.Root, filepath.FromSlash(rel)))
+		}
+	}
+
+	return d, nil
+}
+
+func (d *Discovery) skip(reason Reason, rel string) {
+	d.Skipped[reason] = append(d.Skipped[reason], rel)
+}
+
+// WriteList writes the included files to file, one per line, in the format
+// clang-format's --files flag expects.
+func (d *Discovery) WriteList(file string) error {
+	buf := bytes.Buffer{}
+	for _, f := range d.Files {
+		buf.WriteString(f)
+		buf.WriteString("\n")
+	}
+
+	err := os.WriteFile(file, buf.Bytes(), 0644)
+	if err != nil {
+		return errors.Wrapf(err, "os.WriteFile %s", file)
+	}
+
+	return nil
+}
+
+// Summary returns a human readable account of what was included, broken down
+// by extension, and what was skipped, broken down by reason.
+func (d *Discovery) Summary() string {
+	buf := bytes.Buffer{}
+
+	byExt := make(map[string]int)
+	for _, f := range d.Files {
+		byExt[path.Ext(f)]++
+	}
+
+	exts := make([]string, 0, len(byExt))
+	for ext := range byExt {
+		exts = append(exts, ext)
+	}
+	slices.Sort(exts)
+
+	buf.WriteString(fmt.Sprintf("Included %d files\n", len(d.Files)))
+	for _, ext := range exts {
+		buf.WriteString(fmt.Sprintf("  %-6s %d\n", ext, byExt[ext]))
+	}
+
+	reasons := make([]string, 0, len(d.Skipped))
+	total := 0
+	for reason, files := range d.Skipped {
+		reasons = append(reasons, string(reason))
+		total += len(files)
+	}
+	slices.Sort(reasons)
+
+	buf.WriteString(fmt.Sprintf("Skipped %d files\n", total))
+	for _, reason := range reasons {
+		files := d.Skipped[Reason(reason)]
+		buf.WriteString(fmt.Sprintf("  %s: %d\n", reason, len(files)))
+
+		// A few examples make it obvious whether a rule is too greedy.
+		for i, f := range files {
+			if i == 3 {
+				buf.WriteString(fmt.Sprintf("    ... and %d more\n", len(files)-i))
+				break
+			}
+			buf.WriteString(fmt.Sprintf("    %s\n", f))
+		}
+	}
+
+	return buf.String()
+}
+
+func resolveExtensions(in []string) ([]string, error) {
+	out := make([]string, 0)
+	for _, e := range in {
+		e = strings.TrimSpace(e)
+		switch {
+		case e == "":
+			continue
+		case strings.HasPrefix(e, dot):
+			out = append(out, e)
+		case ExtensionSets[e] != nil:
+			out = append(out, ExtensionSets[e]...)
+		default:
+			return nil, errors.Errorf("unknown extension set %q", e)
+		}
+	}
+
+	slices.Sort(out)
+
+	return slices.Compact(out), nil
+}
+
+const (
+	dot           = "."
+	attrVendored  = "linguist-vendored"
+	attrGenerated = "linguist-generated"
+)
+
+// linguistAttributes asks git which of the files carry the linguist-vendored
+// or linguist-generated attributes. Outside a git repository nothing is
+// marked.
+func linguistAttributes(root string, files []string) (map[string]map[string]bool, error) {
+	out := make(map[string]map[string]bool)
+	if len(files) == 0 {
+		return out, nil
+	}
+
+	if _, err := os.Stat(filepath.Join(root, ".git")); err != nil {
+		return out, nil
+	}
+
+	ctx, cxl := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cxl()
+
+	var stdOut, stdErr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx,
+		"git",
+		"check-attr",
+		"-z",
+		"--stdin",
+		attrVendored,
+		attrGenerated,
+	)
+	cmd.Dir = root
+	cmd.Stdin = strings.NewReader(strings.Join(files, "\x00") + "\x00")
+	cmd.Stdout = &stdOut
+	cmd.Stderr = &stdErr
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, errors.Wrapf(err, "git check-attr: %s", stdErr.String())
+	}
+
+	// output is a sequence of <path> NUL <attribute> NUL <info> NUL
+	fields := strings.Split(strings.TrimSuffix(stdOut.String(), "\x00"), "\x00")
+	for i := 0; i+2 < len(fields); i += 3 {
+		file, attr, value := fields[i], fields[i+1], fields[i+2]
+		if value != "set" && value != "true" {
+			continue
+		}
+
+		if out[file] == nil {
+			out[file] = make(map[string]bool)
+		}
+		out[file][attr] = true
+	}
+
+	return out, nil
+}
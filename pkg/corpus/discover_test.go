@@ -0,0 +1,247 @@
+package corpus
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		path    string
+		want    bool
+	}{
+		{name: "plain match", pattern: "src/*.c", path: "src/nxt_conf.c", want: true},
+		{name: "star does not cross slash", pattern: "src/*.c", path: "src/nodejs/x.c", want: false},
+		{name: "double star matches zero segments", pattern: "src/**/*.c", path: "src/x.c", want: true},
+		{name: "double star matches many segments", pattern: "src/**/*.c", path: "src/a/b/x.c", want: true},
+		{name: "trailing double star", pattern: "src/**", path: "src/a/b/x.h", want: true},
+		{name: "leading double star", pattern: "**/test", path: "a/b/test", want: true},
+		{name: "no match", pattern: "src/**", path: "docs/x.c", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchGlob(tt.pattern, tt.path); got != tt.want {
+				t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ignoreRules(t *testing.T) {
+	var rules ignoreRules
+	for _, line := range []string{"# comment", "*.o", "build/", "/top.c", "!keep.o", "sub/deep/*.c"} {
+		if r, ok := parseIgnoreLine(line, "."); ok {
+			rules = append(rules, r)
+		}
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{path: "a/b/x.o", want: true},
+		{path: "a/keep.o", want: false},
+		{path: "build", isDir: true, want: true},
+		{path: "build", isDir: false, want: false},
+		{path: "top.c", want: true},
+		{path: "a/top.c", want: false},
+		{path: "sub/deep/x.c", want: true},
+		{path: "other/sub/deep/x.c", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := rules.ignored(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("ignored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	root := t.TempDir()
+
+	files := map[string]string{
+		".gitignore":                     "*.gen.c\n",
+		"src/a.c":                        "",
+		"src/a.h":                        "",
+		"src/b.gen.c":                    "",
+		"src/readme.md":                  "",
+		"src/nodejs/binding.c":           "",
+		"src/third/.clang-format-ignore": "*\n",
+		"src/third/lib.c":                "",
+		"test/t.c":                       "",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Discover(DiscoverConfig{
+		Root:       root,
+		Include:    []string{"src/**"},
+		Exclude:    []string{"src/nodejs/**"},
+		Extensions: []string{"c"},
+	})
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+
+	want := []string{filepath.Join(root, "src", "a.c"), filepath.Join(root, "src", "a.h")}
+	if !slices.Equal(got.Files, want) {
+		t.Errorf("Discover() files = %v, want %v", got.Files, want)
+	}
+
+	wantSkipped := map[Reason][]string{
+		ReasonGitIgnore:         {"src/b.gen.c"},
+		ReasonClangFormatIgnore: {"src/third/.clang-format-ignore", "src/third/lib.c"},
+		ReasonExcluded:          {"src/nodejs/binding.c"},
+		ReasonExtension:         {"src/readme.md"},
+	}
+	for reason, files := range wantSkipped {
+		if !slices.Equal(got.Skipped[reason], files) {
+			t.Errorf("Discover() skipped %q = %v, want %v", reason, got.Skipped[reason], files)
+		}
+	}
+
+	if !slices.Contains(got.Skipped[ReasonNotIncluded], "test/t.c") {
+		t.Errorf("Discover() should skip test/t.c as not included, got %v", got.Skipped)
+	}
+}
+
+func TestDiscover_clangFormatIgnoreIsAnchored(t *testing.T) {
+	root := t.TempDir()
+
+	files := map[string]string{
+		".clang-format-ignore":       "foo.c\nsrc/*\nsrc/*/*.c\n",
+		"foo.c":                      "",
+		"src/x/foo.c":                "",
+		"src/y/foo.c":                "",
+		"src/bar.c":                  "",
+		"src/x/.clang-format-ignore": "",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Discover(DiscoverConfig{Root: root, Extensions: []string{"c"}})
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+
+	// foo.c only ignores the file next to the ignore file, and src/* the
+	// files right in src, like clang-format reads them. src/*/*.c does not
+	// reach into src/x, whose own ignore file replaces the root one.
+	want := []string{filepath.Join(root, "src", "x", "foo.c")}
+	if !slices.Equal(got.Files, want) {
+		t.Errorf("Discover() files = %v, want %v", got.Files, want)
+	}
+
+	wantSkipped := []string{"foo.c", "src/bar.c", "src/y/foo.c"}
+	if !slices.Equal(got.Skipped[ReasonClangFormatIgnore], wantSkipped) {
+		t.Errorf("Discover() skipped %v, want %v", got.Skipped[ReasonClangFormatIgnore], wantSkipped)
+	}
+}
+
+func TestDiscover_untracked(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := t.TempDir()
+	for _, name := range []string{"src/a.c", "src/new.c"} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("int x;\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, args := range [][]string{{"init", "--quiet"}, {"add", "src/a.c"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	got, err := Discover(DiscoverConfig{Root: root, Extensions: []string{"c"}})
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+
+	// a reset would not undo clang-format's changes to src/new.c
+	if want := []string{filepath.Join(root, "src", "a.c")}; !slices.Equal(got.Files, want) {
+		t.Errorf("Discover() files = %v, want %v", got.Files, want)
+	}
+	if want := []string{"src/new.c"}; !slices.Equal(got.Skipped[ReasonUntracked], want) {
+		t.Errorf("Discover() skipped %v as untracked, want %v", got.Skipped[ReasonUntracked], want)
+	}
+}
+
+func TestDiscover_linguist(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := t.TempDir()
+	files := map[string]string{
+		".gitattributes":  "vendor/** linguist-vendored\nsrc/gen.c linguist-generated\nsrc/keep.c -linguist-generated\n",
+		"src/a.c":         "int x;\n",
+		"src/gen.c":       "int x;\n",
+		"src/keep.c":      "int x;\n",
+		"vendor/zlib/z.c": "int x;\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, args := range [][]string{{"init", "--quiet"}, {"add", "."}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	got, err := Discover(DiscoverConfig{Root: root, Extensions: []string{"c"}})
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+
+	// an unset attribute leaves the file in
+	want := []string{filepath.Join(root, "src", "a.c"), filepath.Join(root, "src", "keep.c")}
+	if !slices.Equal(got.Files, want) {
+		t.Errorf("Discover() files = %v, want %v", got.Files, want)
+	}
+	if want := []string{"vendor/zlib/z.c"}; !slices.Equal(got.Skipped[ReasonVendored], want) {
+		t.Errorf("Discover() skipped %v as vendored, want %v", got.Skipped[ReasonVendored], want)
+	}
+	if want := []string{"src/gen.c"}; !slices.Equal(got.Skipped[ReasonGenerated], want) {
+		t.Errorf("Discover() skipped %v as generated, want %v", got.Skipped[ReasonGenerated], want)
+	}
+}
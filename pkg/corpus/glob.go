@@ -0,0 +1,64 @@
+package corpus
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchGlob reports whether name matches pattern. Both are slash separated
+// paths. A "**" segment matches zero or more path segments, every other
+// segment is matched with path.Match.
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(
+		strings.Split(strings.Trim(pattern, "/"), "/"),
+		strings.Split(strings.Trim(name, "/"), "/"),
+	)
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// collapse consecutive ** segments
+			for len(pattern) > 1 && pattern[1] == "**" {
+				pattern = pattern[1:]
+			}
+
+			if len(pattern) == 1 {
+				return true
+			}
+
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		ok, err := path.Match(pattern[0], name[0])
+		if err != nil || !ok {
+			return false
+		}
+
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// matchAny reports whether name matches any of the patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if MatchGlob(p, name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,137 @@
+package corpus
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ignoreRule is a single line of a .gitignore or .clang-format-ignore file.
+type ignoreRule struct {
+	// base is the directory the ignore file lives in, relative to the corpus
+	// root. Patterns are matched relative to it.
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ignoreRules is an ordered list of rules. Later rules take precedence over
+// earlier ones, the same way git evaluates them.
+type ignoreRules []ignoreRule
+
+// loadIgnoreFile reads the ignore file at file with parse and returns its
+// rules with base set to dir. A missing file is not an error, it yields no
+// rules.
+func loadIgnoreFile(file, dir string, parse func(line, dir string) (ignoreRule, bool)) (ignoreRules, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "os.Open %s", file)
+	}
+	defer f.Close()
+
+	var rules ignoreRules
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		rule, ok := parse(scanner.Text(), dir)
+		if ok {
+			rules = append(rules, rule)
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "reading %s", file)
+	}
+
+	return rules, nil
+}
+
+func parseIgnoreLine(line, dir string) (ignoreRule, bool) {
+	// trailing spaces are ignored unless escaped
+	if !strings.HasSuffix(line, `\ `) {
+		line = strings.TrimRight(line, " \t\r")
+	}
+
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: dir}
+
+	switch {
+	case strings.HasPrefix(line, "!"):
+		rule.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	// a slash anywhere but at the end anchors the pattern to base
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	rule.pattern = line
+
+	return rule, true
+}
+
+// parseClangFormatIgnoreLine reads a line of a .clang-format-ignore file.
+// Unlike in a .gitignore, every pattern is a path relative to the directory
+// of the file, so one without a slash only matches a file right next to it.
+func parseClangFormatIgnoreLine(line, dir string) (ignoreRule, bool) {
+	rule, ok := parseIgnoreLine(line, dir)
+	rule.anchored = true
+
+	return rule, ok
+}
+
+func (r ignoreRule) matches(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+
+	if r.base != "" && r.base != "." {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = strings.TrimPrefix(rel, r.base+"/")
+	}
+
+	if r.anchored {
+		return MatchGlob(r.pattern, rel)
+	}
+
+	return MatchGlob(r.pattern, path.Base(rel))
+}
+
+// ignored reports whether the path rel, relative to the corpus root, is
+// ignored by the rules.
+func (rules ignoreRules) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range rules {
+		if r.matches(rel, isDir) {
+			ignored = !r.negate
+		}
+	}
+
+	return ignored
+}
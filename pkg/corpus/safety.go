@@ -0,0 +1,233 @@
+package corpus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DirtyError is returned by CheckSafe when the corpus has uncommitted changes
+// that resetting it between evaluations would destroy.
+type DirtyError struct {
+	Dir string
+	// Changes are the lines of git status --porcelain for the tracked files
+	// that differ from HEAD, e.g. " M src/nxt_conf.c".
+	Changes []string
+}
+
+func (e *DirtyError) Error() string {
+	buf := bytes.Buffer{}
+	buf.WriteString(fmt.Sprintf("refusing to run: %s has uncommitted changes to %d tracked files,"+
+		" and the corpus is reset with `git reset --hard` after every evaluation. These would be lost:\n",
+		e.Dir, len(e.Changes)))
+
+	for _, c := range e.Changes {
+		buf.WriteString(fmt.Sprintf("  %s\n", c))
+	}
+
+	buf.WriteString("commit or stash them first, or run on a disposable clone with -clone")
+
+	return buf.String()
+}
+
+// CheckSafe makes sure dir is the root of a git repository with no changes to
+// tracked files, so that resetting it cannot destroy anyone's work. If
+// expectRemote is not empty, the URL of the origin remote must contain it.
+// Untracked files are left to CheckTracked: only those clang-format is given
+// are at risk.
+func CheckSafe(ctx context.Context, dir, expectRemote string) error {
+	toplevel, err := git(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return errors.Wrapf(err, "%s is not a git repository", dir)
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return errors.Wrapf(err, "filepath.Abs %s", dir)
+	}
+
+	abs, err = filepath.EvalSymlinks(abs)
+	if err != nil {
+		return errors.Wrapf(err, "filepath.EvalSymlinks %s", dir)
+	}
+
+	toplevel = strings.TrimSpace(toplevel)
+	if filepath.Clean(toplevel) != abs {
+		return errors.Errorf("refusing to run: %s is not the root of a git repository, it is inside %s."+
+			" Resetting it would reset every change in %s", dir, toplevel, toplevel)
+	}
+
+	if expectRemote != "" {
+		url, err := git(ctx, dir, "remote", "get-url", "origin")
+		if err != nil {
+			return errors.Wrapf(err, "refusing to run: %s has no origin remote, expected one containing %q",
+				dir, expectRemote)
+		}
+
+		url = strings.TrimSpace(url)
+		if !strings.Contains(url, expectRemote) {
+			return errors.Errorf("refusing to run: origin of %s is %s, expected it to contain %q",
+				dir, url, expectRemote)
+		}
+	}
+
+	status, err := git(ctx, dir, "status", "--porcelain=v1", "-z", "--untracked-files=no")
+	if err != nil {
+		return errors.Wrap(err, "git status")
+	}
+
+	changes := parsePorcelainZ(status)
+	if len(changes) > 0 {
+		return &DirtyError{Dir: dir, Changes: changes}
+	}
+
+	return nil
+}
+
+// UntrackedError is returned by CheckTracked when clang-format would rewrite
+// files git does not track. Resetting the corpus does not restore them, so
+// their content would be lost and every candidate's output would leak into
+// the evaluations after it.
+type UntrackedError struct {
+	Dir   string
+	Files []string
+}
+
+func (e *UntrackedError) Error() string {
+	buf := bytes.Buffer{}
+	buf.WriteString(fmt.Sprintf("refusing to run: %d of the files to format in %s are not tracked by git,"+
+		" and `git reset --hard` after every evaluation would not restore them:\n", len(e.Files), e.Dir))
+
+	for i, f := range e.Files {
+		if i == 10 {
+			buf.WriteString(fmt.Sprintf("  ... and %d more\n", len(e.Files)-i))
+			break
+		}
+		buf.WriteString(fmt.Sprintf("  %s\n", f))
+	}
+
+	buf.WriteString("commit them, or leave them out of the files list")
+
+	return buf.String()
+}
+
+// CheckTracked makes sure git tracks every one of files, given relative to
+// the root of the repository at dir.
+func CheckTracked(ctx context.Context, dir string, files []string) error {
+	tracked, err := trackedFiles(ctx, dir)
+	if err != nil {
+		return err
+	}
+
+	untracked := make([]string, 0)
+	for _, f := range files {
+		if !tracked[f] {
+			untracked = append(untracked, f)
+		}
+	}
+
+	if len(untracked) > 0 {
+		return &UntrackedError{Dir: dir, Files: untracked}
+	}
+
+	return nil
+}
+
+// trackedFiles returns the files git tracks in the repository at dir,
+// relative to it.
+func trackedFiles(ctx context.Context, dir string) (map[string]bool, error) {
+	out, err := git(ctx, dir, "ls-files", "-z")
+	if err != nil {
+		return nil, errors.Wrap(err, "git ls-files")
+	}
+
+	tracked := make(map[string]bool)
+	for _, f := range strings.Split(strings.TrimSuffix(out, "\x00"), "\x00") {
+		if f != "" {
+			tracked[f] = true
+		}
+	}
+
+	return tracked, nil
+}
+
+// parsePorcelainZ turns git status --porcelain=v1 -z output into one line per
+// entry. Renames carry their original path in the following field, it is
+// folded into the same line.
+func parsePorcelainZ(out string) []string {
+	changes := make([]string, 0)
+
+	fields := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	for i := 0; i < len(fields); i++ {
+		entry := fields[i]
+		if len(entry) < 4 {
+			continue
+		}
+
+		if entry[0] == 'R' || entry[0] == 'C' {
+			if i+1 < len(fields) {
+				entry = fmt.Sprintf("%s (from %s)", entry, fields[i+1])
+			}
+			i++
+		}
+
+		changes = append(changes, entry)
+	}
+
+	return changes
+}
+
+// Clone makes a throwaway local clone of the repository at dir inside parent
+// and returns its path. Only committed work ends up in the clone. The caller
+// removes it with os.RemoveAll when done.
+func Clone(ctx context.Context, dir, parent string) (string, error) {
+	source, err := filepath.Abs(dir)
+	if err != nil {
+		return "", errors.Wrapf(err, "filepath.Abs %s", dir)
+	}
+
+	target, err := os.MkdirTemp(parent, ".corpus-clone-")
+	if err != nil {
+		return "", errors.Wrap(err, "os.MkdirTemp")
+	}
+
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		_ = os.RemoveAll(target)
+		return "", errors.Wrapf(err, "filepath.Abs %s", target)
+	}
+
+	_, err = git(ctx, parent, "clone", "--quiet", "--local", source, absTarget)
+	if err != nil {
+		_ = os.RemoveAll(target)
+		return "", errors.Wrapf(err, "cloning %s", dir)
+	}
+
+	return target, nil
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	gitCtx, gitCxl := context.WithTimeout(ctx, 2*time.Minute)
+	defer gitCxl()
+
+	var stdOut, stdErr bytes.Buffer
+
+	cmd := exec.CommandContext(gitCtx, "git", append([]string{"--no-pager"}, args...)...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdOut
+	cmd.Stderr = &stdErr
+
+	err := cmd.Run()
+	if err != nil {
+		return "", errors.Wrapf(err, "git %s: %s", args[0], strings.TrimSpace(stdErr.String()))
+	}
+
+	return stdOut.String(), nil
+}
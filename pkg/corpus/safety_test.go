@@ -0,0 +1,113 @@
+package corpus
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func Test_parsePorcelainZ(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{name: "empty", in: "", want: []string{}},
+		{
+			name: "modified and staged",
+			in:   " M src/a.c\x00M  src/b.h\x00",
+			want: []string{" M src/a.c", "M  src/b.h"},
+		},
+		{
+			name: "rename carries the old path",
+			in:   "R  src/new.c\x00src/old.c\x00 D src/gone.c\x00",
+			want: []string{"R  src/new.c (from src/old.c)", " D src/gone.c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePorcelainZ(tt.in); !slices.Equal(got, tt.want) {
+				t.Errorf("parsePorcelainZ() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckSafe(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"},
+			args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	run("init", "--quiet")
+	run("remote", "add", "origin", "git@github.com:nginx/unit.git")
+	if err := os.WriteFile(filepath.Join(dir, "a.c"), []byte("int a;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run("add", "a.c")
+	run("commit", "--quiet", "-m", "initial")
+
+	if err := CheckSafe(ctx, dir, "nginx/unit"); err != nil {
+		t.Fatalf("CheckSafe() on a clean repository = %v", err)
+	}
+
+	if err := CheckSafe(ctx, dir, "nginx/njs"); err == nil {
+		t.Errorf("CheckSafe() with a different remote should fail")
+	}
+
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckSafe(ctx, sub, ""); err == nil {
+		t.Errorf("CheckSafe() on a subdirectory should fail")
+	}
+
+	// an untracked file is only at risk if clang-format is given it, which
+	// is for CheckTracked to catch
+	if err := os.WriteFile(filepath.Join(dir, "b.c"), []byte("int b;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckSafe(ctx, dir, ""); err != nil {
+		t.Errorf("CheckSafe() with only untracked files = %v", err)
+	}
+	if err := CheckTracked(ctx, dir, []string{"a.c"}); err != nil {
+		t.Errorf("CheckTracked() with tracked files = %v", err)
+	}
+
+	var untracked *UntrackedError
+	if err := CheckTracked(ctx, dir, []string{"a.c", "b.c"}); !errors.As(err, &untracked) {
+		t.Fatalf("CheckTracked() with an untracked file = %v, want an UntrackedError", err)
+	}
+	if !slices.Equal(untracked.Files, []string{"b.c"}) {
+		t.Errorf("UntrackedError.Files = %q, want [\"b.c\"]", untracked.Files)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "a.c"), []byte("int a = 1;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var dirty *DirtyError
+	if err := CheckSafe(ctx, dir, ""); !errors.As(err, &dirty) {
+		t.Fatalf("CheckSafe() on a dirty repository = %v, want a DirtyError", err)
+	}
+	if !slices.Equal(dirty.Changes, []string{" M a.c"}) {
+		t.Errorf("DirtyError.Changes = %q, want [\" M a.c\"]", dirty.Changes)
+	}
+}
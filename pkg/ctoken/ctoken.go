@@ -0,0 +1,155 @@
+package ctoken
+
+import (
+	"strings"
+)
+
+// Token is a C or C++ token together with the whitespace in front of it.
+type Token struct {
+	Text string
+
+	// Space is the whitespace between the previous token and this one,
+	// including newlines and escaped newlines.
+	Space string
+}
+
+// punctuators are the multi character punctuators, longest first so the
+// first match is the longest one.
+var punctuators = []string{
+	"<<=", ">>=", "...", "->*", "<=>",
+	"->", "++", "--", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||",
+	"+=", "-=", "*=", "/=", "%=", "&=", "^=", "|=", "##", "::", ".*",
+}
+
+// Tokenize splits src into tokens. It is not a full lexer: it knows enough
+// about comments, literals and punctuators to tell where the whitespace
+// between tokens is, which is all a formatter should be changing. The
+// whitespace after the last token is returned separately.
+func Tokenize(src string) ([]Token, string) {
+	tokens := make([]Token, 0, len(src)/4)
+
+	i := 0
+	for {
+		start := i
+		i = skipSpace(src, i)
+		space := src[start:i]
+
+		if i >= len(src) {
+			return tokens, space
+		}
+
+		end := tokenEnd(src, i)
+		tokens = append(tokens, Token{Text: src[i:end], Space: space})
+		i = end
+	}
+}
+
+func skipSpace(src string, i int) int {
+	for i < len(src) {
+		switch {
+		case isSpace(src[i]):
+			i++
+		case src[i] == '\\' && i+1 < len(src) && src[i+1] == '\n':
+			i += 2
+		case src[i] == '\\' && i+2 < len(src) && src[i+1] == '\r' && src[i+2] == '\n':
+			i += 3
+		default:
+			return i
+		}
+	}
+
+	return i
+}
+
+func tokenEnd(src string, i int) int {
+	c := src[i]
+
+	switch {
+	case strings.HasPrefix(src[i:], "//"):
+		end := strings.IndexByte(src[i:], '\n')
+		if end < 0 {
+			return len(src)
+		}
+		return i + end
+	case strings.HasPrefix(src[i:], "/*"):
+		end := strings.Index(src[i+2:], "*/")
+		if end < 0 {
+			return len(src)
+		}
+		return i + 2 + end + 2
+	case c == '"' || c == '\'':
+		return quotedEnd(src, i)
+	case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+		return numberEnd(src, i)
+	case isIdentStart(c):
+		j := i + 1
+		for j < len(src) && isIdentPart(src[j]) {
+			j++
+		}
+		return j
+	}
+
+	for _, p := range punctuators {
+		if strings.HasPrefix(src[i:], p) {
+			return i + len(p)
+		}
+	}
+
+	return i + 1
+}
+
+// quotedEnd finds the end of a string or character literal, honouring
+// escapes. An unterminated literal ends at the end of the line.
+func quotedEnd(src string, i int) int {
+	quote := src[i]
+	j := i + 1
+	for j < len(src) {
+		switch src[j] {
+		case '\\':
+			j += 2
+			continue
+		case quote:
+			return j + 1
+		case '\n':
+			return j
+		}
+		j++
+	}
+
+	return len(src)
+}
+
+// numberEnd follows the preprocessing number rules: digits, letters,
+// underscores, dots, digit separators and signed exponents.
+func numberEnd(src string, i int) int {
+	j := i + 1
+	for j < len(src) {
+		c := src[j]
+		switch {
+		case (c == '+' || c == '-') && strings.ContainsRune("eEpP", rune(src[j-1])):
+			j++
+		case isIdentPart(c) || c == '.' || c == '\'':
+			j++
+		default:
+			return j
+		}
+	}
+
+	return j
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
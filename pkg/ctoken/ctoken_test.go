@@ -0,0 +1,77 @@
+package ctoken
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name         string
+		src          string
+		want         []Token
+		wantTrailing string
+	}{
+		{
+			name: "declaration",
+			src:  "static int  x = 0x1fUL;\n",
+			want: []Token{
+				{Text: "static"}, {Text: "int", Space: " "}, {Text: "x", Space: "  "},
+				{Text: "=", Space: " "}, {Text: "0x1fUL", Space: " "}, {Text: ";"},
+			},
+			wantTrailing: "\n",
+		},
+		{
+			name: "punctuators",
+			src:  "a->b<<=c...d",
+			want: []Token{
+				{Text: "a"}, {Text: "->"}, {Text: "b"}, {Text: "<<="}, {Text: "c"}, {Text: "..."}, {Text: "d"},
+			},
+		},
+		{
+			name: "literals keep their spaces",
+			src:  `f("a \" b", ' ')`,
+			want: []Token{
+				{Text: "f"}, {Text: "("}, {Text: `"a \" b"`}, {Text: ","}, {Text: "' '", Space: " "}, {Text: ")"},
+			},
+		},
+		{
+			name: "comments are single tokens",
+			src:  "x; // trailing  comment\n/* block\n * comment */\ny",
+			want: []Token{
+				{Text: "x"}, {Text: ";"}, {Text: "// trailing  comment", Space: " "},
+				{Text: "/* block\n * comment */", Space: "\n"}, {Text: "y", Space: "\n"},
+			},
+		},
+		{
+			name: "escaped newlines are whitespace",
+			src:  "#define A \\\n    1\n",
+			want: []Token{
+				{Text: "#"}, {Text: "define"}, {Text: "A", Space: " "}, {Text: "1", Space: " \\\n    "},
+			},
+			wantTrailing: "\n",
+		},
+		{
+			name: "exponents",
+			src:  "1.5e-3+x",
+			want: []Token{{Text: "1.5e-3"}, {Text: "+"}, {Text: "x"}},
+		},
+		{
+			name:         "only whitespace",
+			src:          "  \n",
+			want:         []Token{},
+			wantTrailing: "  \n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, trailing := Tokenize(tt.src)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize() = %q, want %q", got, tt.want)
+			}
+			if trailing != tt.wantTrailing {
+				t.Errorf("Tokenize() trailing = %q, want %q", trailing, tt.wantTrailing)
+			}
+		})
+	}
+}
@@ -1,2 +1,243 @@
 package diff
 
+import (
+	"bytes"
+)
+
+// Op is what happened to a line.
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+func (o Op) String() string {
+	switch o {
+	case Delete:
+		return "-"
+	case Insert:
+		return "+"
+	default:
+		return " "
+	}
+}
+
+// Line is one line of a diff.
+type Line struct {
+	Op Op
+
+	// Text is the content of the line without its line ending.
+	Text string
+
+	// NoNewline is set for the last line of a file that does not end with a
+	// newline.
+	NoNewline bool
+
+	// OldLine and NewLine are the 1 based line numbers in the old and new
+	// file. OldLine is 0 for inserted lines, NewLine is 0 for deleted ones.
+	OldLine int
+	NewLine int
+}
+
+// Lines computes a line by line diff between a and b using Myers' algorithm.
+// The result holds every line of both inputs: equal lines once, deleted lines
+// before the lines inserted in their place.
+func Lines(a, b []byte) []Line {
+	oldLines := splitLines(a)
+	newLines := splitLines(b)
+
+	ops := Strings(oldLines, newLines)
+
+	out := make([]Line, 0, len(ops))
+	i, j := 0, 0
+	for _, op := range ops {
+		var raw string
+		line := Line{Op: op}
+
+		switch op {
+		case Equal:
+			raw = oldLines[i]
+			i++
+			j++
+			line.OldLine, line.NewLine = i, j
+		case Delete:
+			raw = oldLines[i]
+			i++
+			line.OldLine = i
+		case Insert:
+			raw = newLines[j]
+			j++
+			line.NewLine = j
+		}
+
+		line.Text, line.NoNewline = trimNewline(raw)
+		out = append(out, line)
+	}
+
+	return out
+}
+
+// Strings computes the edit script turning a into b using Myers' algorithm.
+// Deletions come before the insertions that replace them.
+func Strings(a, b []string) []Op {
+	// Intern the elements so the comparisons in the hot loop are on ints.
+	ids := make(map[string]int)
+	intern := func(in []string) []int {
+		out := make([]int, len(in))
+		for i, s := range in {
+			id, ok := ids[s]
+			if !ok {
+				id = len(ids)
+				ids[s] = id
+			}
+			out[i] = id
+		}
+
+		return out
+	}
+
+	return myers(intern(a), intern(b))
+}
+
+// splitLines splits in after every newline, keeping the newlines so a last
+// line with and without one compare different.
+func splitLines(in []byte) []string {
+	lines := make([]string, 0, bytes.Count(in, []byte("\n"))+1)
+	for len(in) > 0 {
+		i := bytes.IndexByte(in, '\n')
+		if i < 0 {
+			lines = append(lines, string(in))
+			break
+		}
+
+		lines = append(lines, string(in[:i+1]))
+		in = in[i+1:]
+	}
+
+	return lines
+}
+
+func trimNewline(raw string) (string, bool) {
+	if len(raw) > 0 && raw[len(raw)-1] == '\n' {
+		return raw[:len(raw)-1], false
+	}
+
+	return raw, true
+}
+
+// myers returns the shortest edit script turning a into b. Common prefixes
+// and suffixes are stripped first, since formatters leave most of a file
+// alone.
+func myers(a, b []int) []Op {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]Op, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, Equal)
+	}
+
+	ops = append(ops, shortestEdit(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+
+	for i := 0; i < suffix; i++ {
+		ops = append(ops, Equal)
+	}
+
+	return ops
+}
+
+// shortestEdit is the greedy O((N+M)D) Myers algorithm. For every edit
+// distance d it keeps the furthest reaching x of the diagonals -d..d, which
+// is all the backtracking needs and is far smaller than a full matrix.
+func shortestEdit(a, b []int) []Op {
+	n, m := len(a), len(b)
+	maxD := n + m
+	offset := maxD + 1
+
+	v := make([]int, 2*maxD+3)
+	trace := make([][]int, 0)
+
+	found := false
+	for d := 0; d <= maxD && !found; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				found = true
+			}
+		}
+
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+	}
+
+	// Walk back from the end, collecting the script in reverse.
+	ops := make([]Op, 0, n+m)
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, Equal)
+			x--
+			y--
+		}
+
+		if x == prevX {
+			ops = append(ops, Insert)
+		} else {
+			ops = append(ops, Delete)
+		}
+
+		x, y = prevX, prevY
+	}
+
+	for x > 0 && y > 0 {
+		ops = append(ops, Equal)
+		x--
+		y--
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+
+	return ops
+}
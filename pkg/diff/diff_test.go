@@ -0,0 +1,192 @@
+package diff
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		want     string
+		wantStat NumStat
+	}{
+		{
+			name:     "identical",
+			a:        "a\nb\n",
+			b:        "a\nb\n",
+			want:     "",
+			wantStat: NumStat{},
+		},
+		{
+			name: "one line changed",
+			a:    "a\nb\nc\n",
+			b:    "a\nB\nc\n",
+			want: "--- a\n+++ b\n" +
+				"@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			wantStat: NumStat{Added: 1, Removed: 1},
+		},
+		{
+			name: "far apart changes make two hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			b:    "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			want: "--- a\n+++ b\n" +
+				"@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n" +
+				"@@ -9,4 +10,3 @@\n 9\n 10\n 11\n-12\n",
+			wantStat: NumStat{Added: 1, Removed: 1},
+		},
+		{
+			name: "close changes share a hunk",
+			a:    "1\n2\n3\n4\n5\n6\n7\n",
+			b:    "1\nx\n3\n4\n5\n6\ny\n",
+			want: "--- a\n+++ b\n" +
+				"@@ -1,7 +1,7 @@\n 1\n-2\n+x\n 3\n 4\n 5\n 6\n-7\n+y\n",
+			wantStat: NumStat{Added: 2, Removed: 2},
+		},
+		{
+			name: "missing newline at end of file",
+			a:    "a\nb\n",
+			b:    "a\nb",
+			want: "--- a\n+++ b\n" +
+				"@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+			wantStat: NumStat{Added: 1, Removed: 1},
+		},
+		{
+			name:     "everything new",
+			a:        "",
+			b:        "a\nb\n",
+			want:     "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+			wantStat: NumStat{Added: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotStat := Bytes("a", "b", []byte(tt.a), []byte(tt.b))
+			if got != tt.want {
+				t.Errorf("Bytes() diff =\n%s\nwant\n%s", got, tt.want)
+			}
+			if gotStat != tt.wantStat {
+				t.Errorf("Bytes() stat = %+v, want %+v", gotStat, tt.wantStat)
+			}
+		})
+	}
+}
+
+// TestLines_roundTrip checks on random inputs that the old and new files can
+// be rebuilt from the diff and that the edit script is as short as an LCS
+// based one.
+func TestLines_roundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	alphabet := []string{"a\n", "b\n", "c\n", "{\n", "}\n"}
+
+	gen := func() string {
+		n := r.Intn(30)
+		sb := strings.Builder{}
+		for i := 0; i < n; i++ {
+			sb.WriteString(alphabet[r.Intn(len(alphabet))])
+		}
+		return sb.String()
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := gen(), gen()
+		lines := Lines([]byte(a), []byte(b))
+
+		oldSB, newSB := strings.Builder{}, strings.Builder{}
+		for _, l := range lines {
+			if l.Op != Insert {
+				oldSB.WriteString(l.Text + "\n")
+			}
+			if l.Op != Delete {
+				newSB.WriteString(l.Text + "\n")
+			}
+		}
+
+		if oldSB.String() != a || newSB.String() != b {
+			t.Fatalf("round trip failed for %q -> %q", a, b)
+		}
+
+		s := Stat(lines)
+		want := len(splitLines([]byte(a))) + len(splitLines([]byte(b))) -
+			2*lcs(splitLines([]byte(a)), splitLines([]byte(b)))
+		if s.Added+s.Removed != want {
+			t.Fatalf("edit script for %q -> %q has %d edits, want %d", a, b, s.Added+s.Removed, want)
+		}
+	}
+}
+
+// TestParseUnified checks on random inputs that Unified's output reads back
+// into the hunks it was written from.
+func TestParseUnified(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	alphabet := []string{"a\n", "b\n", "", "  c\n", "{\n", "}\n", "\n"}
+
+	gen := func() string {
+		n := r.Intn(40)
+		sb := strings.Builder{}
+		for i := 0; i < n; i++ {
+			sb.WriteString(alphabet[r.Intn(len(alphabet))])
+		}
+		if r.Intn(4) == 0 {
+			sb.WriteString("end")
+		}
+		return sb.String()
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := gen(), gen()
+		want := Hunks(Lines([]byte(a), []byte(b)), DefaultContext)
+
+		got, err := ParseUnified(Unified("a", "b", want))
+		if err != nil {
+			t.Fatalf("ParseUnified() error = %v for %q -> %q", err, a, b)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("ParseUnified() = %+v\nwant %+v\nfor %q -> %q", got, want, a, b)
+		}
+	}
+}
+
+func TestParseUnified_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{name: "bad header", in: "@@ -x +1 @@\n+a\n"},
+		{name: "hunk too short", in: "@@ -1,2 +1,2 @@\n a\n"},
+		{name: "hunk too long", in: "@@ -1 +1 @@\n a\n b\n"},
+		{name: "not a diff line", in: "@@ -1 +1 @@\n*a\n"},
+		{name: "no newline marker first", in: "@@ -1 +1 @@\n\\ No newline at end of file\n a\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseUnified(tt.in); err == nil {
+				t.Errorf("ParseUnified(%q) returned no error", tt.in)
+			}
+		})
+	}
+}
+
+func lcs(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			switch {
+			case a[i-1] == b[j-1]:
+				dp[i][j] = dp[i-1][j-1] + 1
+			case dp[i-1][j] > dp[i][j-1]:
+				dp[i][j] = dp[i-1][j]
+			default:
+				dp[i][j] = dp[i][j-1]
+			}
+		}
+	}
+	return dp[len(a)][len(b)]
+}
@@ -0,0 +1,280 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultContext is the number of unchanged lines git and diff -u show
+// around a change.
+const DefaultContext = 3
+
+// Hunk is a run of changes together with the surrounding context lines.
+type Hunk struct {
+	// OldStart and NewStart are the 1 based line numbers the hunk starts at.
+	// For an empty range they point at the line before it, like in unified
+	// diff headers.
+	OldStart, OldLines int
+	NewStart, NewLines int
+
+	Lines []Line
+}
+
+// NumStat holds the counts git diff --numstat reports for a file.
+type NumStat struct {
+	Added   int
+	Removed int
+}
+
+// Stat counts the inserted and deleted lines.
+func Stat(lines []Line) NumStat {
+	s := NumStat{}
+	for _, l := range lines {
+		switch l.Op {
+		case Insert:
+			s.Added++
+		case Delete:
+			s.Removed++
+		}
+	}
+
+	return s
+}
+
+// Hunks groups the changes in lines into hunks with up to context unchanged
+// lines on either side. Changes closer than 2*context lines share a hunk.
+func Hunks(lines []Line, context int) []Hunk {
+	hunks := make([]Hunk, 0)
+
+	i := 0
+	for i < len(lines) {
+		// find the next change
+		for i < len(lines) && lines[i].Op == Equal {
+			i++
+		}
+		if i == len(lines) {
+			break
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		// extend while the gap between changes is small enough to merge
+		end := i
+		for end < len(lines) {
+			for end < len(lines) && lines[end].Op != Equal {
+				end++
+			}
+
+			gap := end
+			for gap < len(lines) && lines[gap].Op == Equal {
+				gap++
+			}
+
+			if gap == len(lines) || gap-end > 2*context {
+				break
+			}
+
+			end = gap
+		}
+
+		stop := end + context
+		if stop > len(lines) {
+			stop = len(lines)
+		}
+
+		hunks = append(hunks, newHunk(lines[start:stop], lines[:start]))
+		i = stop
+	}
+
+	return hunks
+}
+
+// newHunk builds a hunk from its lines. before holds every line preceding it,
+// which is where the start line numbers come from.
+func newHunk(lines, before []Line) Hunk {
+	h := Hunk{Lines: lines}
+
+	oldSeen, newSeen := 0, 0
+	for _, l := range before {
+		if l.Op != Insert {
+			oldSeen++
+		}
+		if l.Op != Delete {
+			newSeen++
+		}
+	}
+
+	for _, l := range lines {
+		if l.Op != Insert {
+			h.OldLines++
+		}
+		if l.Op != Delete {
+			h.NewLines++
+		}
+	}
+
+	h.OldStart = oldSeen + 1
+	if h.OldLines == 0 {
+		h.OldStart = oldSeen
+	}
+
+	h.NewStart = newSeen + 1
+	if h.NewLines == 0 {
+		h.NewStart = newSeen
+	}
+
+	return h
+}
+
+// Header returns the @@ line of the hunk.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+func hunkRange(start, lines int) string {
+	if lines == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+// Unified renders the hunks as a unified diff between the files oldName and
+// newName. It returns an empty string when there are no hunks.
+func Unified(oldName, newName string, hunks []Hunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	buf := bytes.Buffer{}
+	buf.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", oldName, newName))
+
+	for _, h := range hunks {
+		buf.WriteString(h.Header())
+		buf.WriteString("\n")
+
+		for _, l := range h.Lines {
+			buf.WriteString(l.Op.String())
+			buf.WriteString(l.Text)
+			buf.WriteString("\n")
+
+			if l.NoNewline {
+				buf.WriteString("\\ No newline at end of file\n")
+			}
+		}
+	}
+
+	return buf.String()
+}
+
+// ParseUnified reads back the hunks of a unified diff written by Unified. The
+// file header lines are skipped.
+func ParseUnified(s string) ([]Hunk, error) {
+	hunks := make([]Hunk, 0)
+	if s == "" {
+		return hunks, nil
+	}
+
+	var current *Hunk
+	oldLine, newLine := 0, 0
+	oldLeft, newLeft := 0, 0
+
+	for n, raw := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(raw, "@@ "):
+			if current != nil && (oldLeft != 0 || newLeft != 0) {
+				return nil, errors.Errorf("line %d: hunk %s ends early", n+1, current.Header())
+			}
+
+			h := Hunk{}
+			_, err := fmt.Sscanf(normalizeHeader(raw), "@@ -%d,%d +%d,%d @@",
+				&h.OldStart, &h.OldLines, &h.NewStart, &h.NewLines)
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d: hunk header %q", n+1, raw)
+			}
+
+			hunks = append(hunks, h)
+			current = &hunks[len(hunks)-1]
+			oldLine, newLine = h.OldStart, h.NewStart
+			oldLeft, newLeft = h.OldLines, h.NewLines
+		case current == nil:
+			// the --- and +++ lines before the first hunk
+			continue
+		case strings.HasPrefix(raw, "\\"):
+			if len(current.Lines) == 0 {
+				return nil, errors.Errorf("line %d: no line to end without a newline", n+1)
+			}
+
+			current.Lines[len(current.Lines)-1].NoNewline = true
+		default:
+			l := Line{Op: Equal}
+			if raw != "" {
+				l.Text = raw[1:]
+
+				switch raw[0] {
+				case ' ':
+				case '-':
+					l.Op = Delete
+				case '+':
+					l.Op = Insert
+				default:
+					return nil, errors.Errorf("line %d: %q is not part of a hunk", n+1, raw)
+				}
+			}
+
+			if l.Op != Insert {
+				l.OldLine = oldLine
+				oldLine++
+				oldLeft--
+			}
+			if l.Op != Delete {
+				l.NewLine = newLine
+				newLine++
+				newLeft--
+			}
+
+			if oldLeft < 0 || newLeft < 0 {
+				return nil, errors.Errorf("line %d: hunk %s is longer than its header says", n+1,
+					current.Header())
+			}
+
+			current.Lines = append(current.Lines, l)
+		}
+	}
+
+	if current != nil && (oldLeft != 0 || newLeft != 0) {
+		return nil, errors.Errorf("hunk %s ends early", current.Header())
+	}
+
+	return hunks, nil
+}
+
+// normalizeHeader spells out the line counts Header leaves out when they are
+// 1, so every header scans the same way.
+func normalizeHeader(header string) string {
+	fields := strings.Fields(header)
+	if len(fields) < 4 {
+		return header
+	}
+
+	for i := 1; i <= 2; i++ {
+		if !strings.Contains(fields[i], ",") {
+			fields[i] += ",1"
+		}
+	}
+
+	return strings.Join(fields[:4], " ")
+}
+
+// Bytes diffs a and b and returns the unified diff along with the line counts.
+func Bytes(oldName, newName string, a, b []byte) (string, NumStat) {
+	lines := Lines(a, b)
+
+	return Unified(oldName, newName, Hunks(lines, DefaultContext)), Stat(lines)
+}
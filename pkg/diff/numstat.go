@@ -0,0 +1,185 @@
+package diff
+
+import (
+	"bytes"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// NumStatEntry is one file of git diff --numstat output.
+type NumStatEntry struct {
+	NumStat
+
+	// Path is where the file is now. For renames and copies OldPath is where
+	// it came from, otherwise it is empty.
+	Path    string
+	OldPath string
+
+	// Binary is set for files git does not count lines in. Added and Removed
+	// are 0 for them.
+	Binary bool
+}
+
+// ParseNumStat reads git diff --numstat output. Renames can be in either of
+// the forms git prints them, "old => new" and "dir/{old => new}/file", and
+// paths git quoted are unquoted. A path that itself contains " => " cannot be
+// told apart from a rename here, ParseNumStatZ does not have that problem.
+func ParseNumStat(out []byte) ([]NumStatEntry, error) {
+	entries := make([]NumStatEntry, 0)
+
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+
+		e, name, err := parseCounts(line)
+		if err != nil {
+			return nil, err
+		}
+
+		e.Path, e.OldPath, err = parseNumStatPath(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %q", line)
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// ParseNumStatZ reads git diff --numstat -z output, where every record ends in
+// a NUL and paths are never quoted. A rename leaves the path of its record
+// empty and is followed by the old and the new path as records of their own.
+func ParseNumStatZ(out []byte) ([]NumStatEntry, error) {
+	entries := make([]NumStatEntry, 0)
+
+	fields := bytes.Split(out, []byte{0})
+	for i := 0; i < len(fields); i++ {
+		record := string(fields[i])
+		if record == "" {
+			continue
+		}
+
+		e, name, err := parseCounts(record)
+		if err != nil {
+			return nil, err
+		}
+
+		if name == "" {
+			if i+2 >= len(fields) {
+				return nil, errors.Errorf("rename record %q is missing its paths", record)
+			}
+
+			e.OldPath, e.Path = string(fields[i+1]), string(fields[i+2])
+			i += 2
+		} else {
+			e.Path = name
+		}
+
+		if e.Path == "" {
+			return nil, errors.Errorf("record %q has no path", record)
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// parseCounts splits the added and removed columns off a record and returns
+// what is left, the path part.
+func parseCounts(record string) (NumStatEntry, string, error) {
+	added, rest, ok := strings.Cut(record, "\t")
+	if !ok {
+		return NumStatEntry{}, "", errors.Errorf("record %q has no tab separated columns", record)
+	}
+
+	removed, name, ok := strings.Cut(rest, "\t")
+	if !ok {
+		return NumStatEntry{}, "", errors.Errorf("record %q has only two columns", record)
+	}
+
+	e := NumStatEntry{}
+
+	// binary files get a dash for both counts
+	if added == "-" && removed == "-" {
+		e.Binary = true
+		return e, name, nil
+	}
+
+	var err error
+
+	e.Added, err = strconv.Atoi(added)
+	if err != nil {
+		return NumStatEntry{}, "", errors.Wrapf(err, "added lines in record %q", record)
+	}
+
+	e.Removed, err = strconv.Atoi(removed)
+	if err != nil {
+		return NumStatEntry{}, "", errors.Wrapf(err, "removed lines in record %q", record)
+	}
+
+	return e, name, nil
+}
+
+// parseNumStatPath returns the new and old path of a path column, the old one
+// being empty unless the column describes a rename.
+func parseNumStatPath(name string) (string, string, error) {
+	if strings.HasPrefix(name, `"`) && strings.HasSuffix(name, `"`) && !strings.Contains(name, " => ") {
+		unquoted, err := unquote(name)
+		return unquoted, "", err
+	}
+
+	open := strings.Index(name, "{")
+	arrow := strings.Index(name, " => ")
+	closing := strings.LastIndex(name, "}")
+
+	switch {
+	case arrow < 0:
+		return name, "", nil
+	case open >= 0 && open < arrow && arrow < closing:
+		// dir/{old => new}/file, either side of the arrow may be empty
+		prefix, suffix := name[:open], name[closing+1:]
+		oldPart, newPart := name[open+1:arrow], name[arrow+len(" => "):closing]
+
+		return joinRename(prefix, newPart, suffix), joinRename(prefix, oldPart, suffix), nil
+	default:
+		oldPath, err := unquote(name[:arrow])
+		if err != nil {
+			return "", "", err
+		}
+
+		newPath, err := unquote(name[arrow+len(" => "):])
+		if err != nil {
+			return "", "", err
+		}
+
+		return newPath, oldPath, nil
+	}
+}
+
+// joinRename puts a path back together around one side of a {old => new}
+// rename, dropping the doubled slash an empty side leaves.
+func joinRename(prefix, middle, suffix string) string {
+	return path.Clean(prefix + middle + suffix)
+}
+
+// unquote undoes git's C-style quoting of unusual paths. Its escapes, octal
+// bytes included, are a subset of Go's.
+func unquote(name string) (string, error) {
+	if !strings.HasPrefix(name, `"`) {
+		return name, nil
+	}
+
+	unquoted, err := strconv.Unquote(name)
+	if err != nil {
+		return "", errors.Wrapf(err, "unquoting path %s", name)
+	}
+
+	return unquoted, nil
+}
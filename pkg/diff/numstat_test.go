@@ -0,0 +1,144 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNumStat(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    []NumStatEntry
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			out:  "",
+			want: []NumStatEntry{},
+		},
+		{
+			name: "plain",
+			out:  "3\t4\tsrc/a.c\n10\t0\tsrc/b.h\n",
+			want: []NumStatEntry{
+				{NumStat: NumStat{Added: 3, Removed: 4}, Path: "src/a.c"},
+				{NumStat: NumStat{Added: 10}, Path: "src/b.h"},
+			},
+		},
+		{
+			name: "binary",
+			out:  "-\t-\tbin.dat\n",
+			want: []NumStatEntry{{Path: "bin.dat", Binary: true}},
+		},
+		{
+			name: "spaces are not quoted",
+			out:  "1\t0\tsp ace.c\n",
+			want: []NumStatEntry{{NumStat: NumStat{Added: 1}, Path: "sp ace.c"}},
+		},
+		{
+			name: "quoted",
+			out:  "1\t0\t\"\\303\\251.c\"\n2\t1\t\"tab\\there.c\"\n",
+			want: []NumStatEntry{
+				{NumStat: NumStat{Added: 1}, Path: "é.c"},
+				{NumStat: NumStat{Added: 2, Removed: 1}, Path: "tab\there.c"},
+			},
+		},
+		{
+			name: "rename",
+			out:  "0\t0\ttop.c => renamed.c\n",
+			want: []NumStatEntry{{Path: "renamed.c", OldPath: "top.c"}},
+		},
+		{
+			name: "rename with braces",
+			out:  "1\t2\tsrc/{a => b}/f.c\n",
+			want: []NumStatEntry{{NumStat: NumStat{Added: 1, Removed: 2}, Path: "src/b/f.c", OldPath: "src/a/f.c"}},
+		},
+		{
+			name: "rename into a new directory",
+			out:  "0\t0\tsrc/{ => sub}/f.c\n",
+			want: []NumStatEntry{{Path: "src/sub/f.c", OldPath: "src/f.c"}},
+		},
+		{
+			name: "quoted rename",
+			out:  "0\t0\t\"a\\tb.c\" => c.c\n",
+			want: []NumStatEntry{{Path: "c.c", OldPath: "a\tb.c"}},
+		},
+		{
+			name:    "missing columns",
+			out:     "3\tsrc/a.c\n",
+			wantErr: true,
+		},
+		{
+			name:    "not a number",
+			out:     "x\t1\tsrc/a.c\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseNumStat([]byte(tt.out))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseNumStat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseNumStat() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNumStatZ(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    []NumStatEntry
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			out:  "",
+			want: []NumStatEntry{},
+		},
+		{
+			name: "git output",
+			out: "-\t-\tbin.dat\x00" +
+				"0\t0\t\x00top.c\x00renamed.c\x00" +
+				"1\t0\tsp ace.c\x00" +
+				"0\t0\t\x00src/a/f.c\x00src/b/f.c\x00" +
+				"1\t0\t\xc3\xa9.c\x00",
+			want: []NumStatEntry{
+				{Path: "bin.dat", Binary: true},
+				{Path: "renamed.c", OldPath: "top.c"},
+				{NumStat: NumStat{Added: 1}, Path: "sp ace.c"},
+				{Path: "src/b/f.c", OldPath: "src/a/f.c"},
+				{NumStat: NumStat{Added: 1}, Path: "é.c"},
+			},
+		},
+		{
+			name: "arrows and newlines are part of the name",
+			out:  "2\t2\ta => b\nc.c\x00",
+			want: []NumStatEntry{{NumStat: NumStat{Added: 2, Removed: 2}, Path: "a => b\nc.c"}},
+		},
+		{
+			name:    "truncated rename",
+			out:     "0\t0\t\x00top.c\x00",
+			wantErr: true,
+		},
+		{
+			name:    "binary in one column only",
+			out:     "-\t3\tx.c\x00",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseNumStatZ([]byte(tt.out))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseNumStatZ() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseNumStatZ() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
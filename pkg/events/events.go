@@ -0,0 +1,165 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Type names an event in the stream.
+type Type string
+
+const (
+	TypeEvaluationStarted  Type = "evaluation_started"
+	TypeEvaluationFinished Type = "evaluation_finished"
+	TypeOptionWinner       Type = "option_winner"
+	TypePassFinished       Type = "pass_finished"
+)
+
+// Event is anything that can be emitted into the stream.
+type Event interface {
+	Type() Type
+}
+
+// EvaluationStarted is emitted before clang-format runs for a candidate.
+type EvaluationStarted struct {
+	Pass   int    `json:"pass"`
+	Option string `json:"option"`
+	Value  string `json:"value"`
+}
+
+func (EvaluationStarted) Type() Type { return TypeEvaluationStarted }
+
+// EvaluationFinished is emitted once a candidate has been scored.
+type EvaluationFinished struct {
+	Pass   int    `json:"pass"`
+	Option string `json:"option"`
+	Value  string `json:"value"`
+	Cost   int    `json:"cost"`
+	// RawCost is Cost before file weights were applied.
+	RawCost int `json:"raw_cost"`
+	// Categories counts the blocks of changed lines by the kind of change
+	// they make, like "indentation" or "brace".
+	Categories map[string]int `json:"categories"`
+	DurationMS int64          `json:"duration_ms"`
+	// ExitCode is clang-format's exit status. A candidate it rejected has
+	// no cost.
+	ExitCode int `json:"exit_code"`
+}
+
+func (EvaluationFinished) Type() Type { return TypeEvaluationFinished }
+
+// OptionWinner is emitted when every value of an option has been evaluated
+// and the best one was kept.
+type OptionWinner struct {
+	Pass    int    `json:"pass"`
+	Option  string `json:"option"`
+	Value   string `json:"value"`
+	Cost    int    `json:"cost"`
+	RawCost int    `json:"raw_cost"`
+	// Irrelevant is true when every value had the same cost, Identical
+	// when they also formatted the code exactly the same way.
+	Irrelevant bool `json:"irrelevant"`
+	Identical  bool `json:"identical"`
+}
+
+func (OptionWinner) Type() Type { return TypeOptionWinner }
+
+// PassFinished is emitted at the end of a pass over the options.
+type PassFinished struct {
+	Pass       int      `json:"pass"`
+	Cost       int      `json:"cost"`
+	RawCost    int      `json:"raw_cost"`
+	Irrelevant []string `json:"irrelevant"`
+}
+
+func (PassFinished) Type() Type { return TypePassFinished }
+
+// Sink receives events.
+type Sink interface {
+	Emit(e Event)
+}
+
+// Discard is a Sink that drops every event.
+type Discard struct{}
+
+func (Discard) Emit(Event) {}
+
+// Multi fans every event out to each of its sinks in order.
+type Multi []Sink
+
+func (m Multi) Emit(e Event) {
+	for _, s := range m {
+		s.Emit(e)
+	}
+}
+
+// JSONLines writes every event as one JSON object per line. The event's
+// fields are flattened next to "type" and "time" so each line can be consumed
+// on its own.
+type JSONLines struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+}
+
+// NewJSONLines returns a sink writing to w.
+func NewJSONLines(w io.Writer) *JSONLines {
+	return &JSONLines{w: w}
+}
+
+// Emit writes the event. Once a write failed, further events are dropped and
+// the error is available from Err.
+func (j *JSONLines) Emit(e Event) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.err != nil {
+		return
+	}
+
+	line, err := encode(e, time.Now())
+	if err != nil {
+		j.err = err
+		return
+	}
+
+	_, err = j.w.Write(line)
+	if err != nil {
+		j.err = errors.Wrap(err, "writing event")
+	}
+}
+
+// Err returns the first error encountered while emitting.
+func (j *JSONLines) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.err
+}
+
+func encode(e Event, at time.Time) ([]byte, error) {
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return nil, errors.Wrapf(err, "json.Marshal %s", e.Type())
+	}
+
+	fields := make(map[string]any)
+	err = json.Unmarshal(raw, &fields)
+	if err != nil {
+		return nil, errors.Wrapf(err, "json.Unmarshal %s", e.Type())
+	}
+
+	fields["type"] = e.Type()
+	fields["time"] = at.UTC().Format(time.RFC3339Nano)
+
+	line, err := json.Marshal(fields)
+	if err != nil {
+		return nil, errors.Wrapf(err, "json.Marshal %s envelope", e.Type())
+	}
+
+	return append(line, '\n'), nil
+}
@@ -0,0 +1,40 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_encode(t *testing.T) {
+	at := time.Date(2024, 11, 5, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		event Event
+		want  string
+	}{
+		{
+			name:  "fields are flattened next to type and time",
+			event: EvaluationStarted{Pass: 1, Option: "IndentWidth", Value: "4"},
+			want: `{"option":"IndentWidth","pass":1,"time":"2024-11-05T10:00:00Z",` +
+				`"type":"evaluation_started","value":"4"}` + "\n",
+		},
+		{
+			name:  "lists are kept",
+			event: PassFinished{Pass: 2, Cost: 17666, RawCost: 18000, Irrelevant: []string{"TabWidth"}},
+			want: `{"cost":17666,"irrelevant":["TabWidth"],"pass":2,"raw_cost":18000,` +
+				`"time":"2024-11-05T10:00:00Z","type":"pass_finished"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encode(tt.event, at)
+			if err != nil {
+				t.Fatalf("encode() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("encode() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
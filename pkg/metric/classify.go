@@ -0,0 +1,185 @@
+package metric
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Category is the kind of change a block of changed lines makes.
+type Category string
+
+const (
+	// CategoryWhitespace is spacing within lines changing, or blank lines
+	// being added or removed.
+	CategoryWhitespace Category = "whitespace"
+	// CategoryIndentation is only the leading whitespace of lines changing.
+	CategoryIndentation Category = "indentation"
+	// CategoryJoinSplit is lines being joined or split somewhere other than
+	// next to a brace.
+	CategoryJoinSplit Category = "join-split"
+	// CategoryBrace is a brace moving onto or off its own line.
+	CategoryBrace Category = "brace"
+	// CategoryToken is anything that changes the code besides whitespace,
+	// like braces or parentheses being added or removed.
+	CategoryToken Category = "token"
+)
+
+// AllCategories lists the categories from the most to the least disruptive,
+// which is also the order Classify checks them in.
+var AllCategories = []Category{
+	CategoryToken,
+	CategoryBrace,
+	CategoryJoinSplit,
+	CategoryIndentation,
+	CategoryWhitespace,
+}
+
+// CategoryCounts holds how many blocks of changed lines fell into each
+// category.
+type CategoryCounts map[Category]int
+
+// Add adds the counts of other to c.
+func (c CategoryCounts) Add(other CategoryCounts) {
+	for k, v := range other {
+		c[k] += v
+	}
+}
+
+// Classify works out what kind of change a block is. When a block does
+// several things at once, the most disruptive one wins.
+func Classify(b Block) Category {
+	oldTokens, oldBreaks := breaks(b.Old)
+	newTokens, newBreaks := breaks(b.New)
+
+	if oldTokens != newTokens {
+		return CategoryToken
+	}
+
+	// Breaks are line endings, recorded as the number of non-whitespace
+	// bytes before them. Compare them as sets: a blank line repeats a
+	// position, it does not move anything.
+	moved := symmetricDifference(oldBreaks, newBreaks)
+	if len(moved) > 0 {
+		for _, p := range moved {
+			if !nextToBrace(oldTokens, p) {
+				return CategoryJoinSplit
+			}
+		}
+
+		return CategoryBrace
+	}
+
+	if len(b.Old) != len(b.New) {
+		return CategoryWhitespace
+	}
+
+	indented := false
+	for i := range b.Old {
+		oldIndent, oldRest := splitIndent(b.Old[i])
+		newIndent, newRest := splitIndent(b.New[i])
+
+		if oldRest != newRest {
+			return CategoryWhitespace
+		}
+
+		if oldIndent != newIndent {
+			indented = true
+		}
+	}
+
+	if !indented {
+		return CategoryWhitespace
+	}
+
+	return CategoryIndentation
+}
+
+// Categories classifies every block of changed lines in the file.
+func Categories(f *File) CategoryCounts {
+	counts := make(CategoryCounts)
+	for _, b := range ChangeBlocks(f.Lines) {
+		counts[Classify(b)]++
+	}
+
+	return counts
+}
+
+// breaks returns the lines joined without whitespace, and for the end of
+// every line the number of non-whitespace bytes preceding it.
+func breaks(lines []string) (string, []int) {
+	tokens := strings.Builder{}
+	positions := make([]int, 0, len(lines))
+
+	for _, l := range lines {
+		for _, r := range l {
+			if !unicode.IsSpace(r) {
+				tokens.WriteRune(r)
+			}
+		}
+
+		positions = append(positions, tokens.Len())
+	}
+
+	return tokens.String(), positions
+}
+
+func symmetricDifference(a, b []int) []int {
+	inA := make(map[int]bool)
+	for _, p := range a {
+		inA[p] = true
+	}
+
+	inB := make(map[int]bool)
+	for _, p := range b {
+		inB[p] = true
+	}
+
+	out := make([]int, 0)
+	for p := range inA {
+		if !inB[p] {
+			out = append(out, p)
+		}
+	}
+	for p := range inB {
+		if !inA[p] {
+			out = append(out, p)
+		}
+	}
+
+	return out
+}
+
+// nextToBrace reports whether the character on either side of position p of
+// the whitespace-free text is a brace.
+func nextToBrace(tokens string, p int) bool {
+	isBrace := func(i int) bool {
+		return i >= 0 && i < len(tokens) && (tokens[i] == '{' || tokens[i] == '}')
+	}
+
+	return isBrace(p-1) || isBrace(p)
+}
+
+func splitIndent(line string) (string, string) {
+	rest := strings.TrimLeft(line, " \t")
+
+	return line[:len(line)-len(rest)], rest
+}
+
+// CategoryCount is a metric counting the blocks of changed lines of one
+// category.
+type CategoryCount struct {
+	Category Category
+}
+
+func (c CategoryCount) Name() string { return string(c.Category) }
+
+func (c CategoryCount) FileCost(f *File) float64 {
+	n := 0
+	for _, b := range ChangeBlocks(f.Lines) {
+		if Classify(b) == c.Category {
+			n++
+		}
+	}
+
+	return float64(n)
+}
@@ -0,0 +1,56 @@
+package metric
+
+import (
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		b    Block
+		want Category
+	}{
+		{
+			name: "space inside a line",
+			b:    Block{Old: []string{"x = (int)y;"}, New: []string{"x = (int) y;"}},
+			want: CategoryWhitespace,
+		},
+		{
+			name: "blank line removed",
+			b:    Block{Old: []string{"", ""}, New: []string{""}},
+			want: CategoryWhitespace,
+		},
+		{
+			name: "reindented",
+			b:    Block{Old: []string{"    a;", "    b;"}, New: []string{"  a;", "  b;"}},
+			want: CategoryIndentation,
+		},
+		{
+			name: "reindented and respaced",
+			b:    Block{Old: []string{"    a = 1;", "    b;"}, New: []string{"  a  = 1;", "  b;"}},
+			want: CategoryWhitespace,
+		},
+		{
+			name: "brace moves to its own line",
+			b:    Block{Old: []string{"int f(void) {"}, New: []string{"int f(void)", "{"}},
+			want: CategoryBrace,
+		},
+		{
+			name: "arguments joined",
+			b:    Block{Old: []string{"f(a,", "  b);"}, New: []string{"f(a, b);"}},
+			want: CategoryJoinSplit,
+		},
+		{
+			name: "braces removed",
+			b:    Block{Old: []string{"if (a) {", "    b;", "}"}, New: []string{"if (a)", "    b;"}},
+			want: CategoryToken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.b); got != tt.want {
+				t.Errorf("Classify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
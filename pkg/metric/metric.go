@@ -0,0 +1,299 @@
+package metric
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/javorszky/go-diff-clang/pkg/diff"
+	"github.com/pkg/errors"
+)
+
+// File is everything a metric gets to see about one file clang-format
+// changed.
+type File struct {
+	// Path is relative to the corpus root, the way git reports it.
+	Path string
+
+	// Stat holds the added and removed line counts.
+	Stat diff.NumStat
+
+	// Lines is the full line diff between the original and formatted file.
+	Lines []diff.Line
+
+	// Hunks groups Lines the way git diff would show them.
+	Hunks []diff.Hunk
+}
+
+// NewFile diffs the original and formatted content of the file at path.
+func NewFile(path string, original, formatted []byte) *File {
+	lines := diff.Lines(original, formatted)
+
+	return &File{
+		Path:  path,
+		Stat:  diff.Stat(lines),
+		Lines: lines,
+		Hunks: diff.Hunks(lines, diff.DefaultContext),
+	}
+}
+
+// FromHunks builds the file from the hunks of its diff, as recorded by an
+// earlier run. Lines then only holds the lines of the hunks, which is every
+// changed line with some context: enough for every metric, none of which
+// looks further than the changes.
+func FromHunks(path string, hunks []diff.Hunk) *File {
+	lines := make([]diff.Line, 0)
+	for _, h := range hunks {
+		lines = append(lines, h.Lines...)
+	}
+
+	return &File{
+		Path:  path,
+		Stat:  diff.Stat(lines),
+		Lines: lines,
+		Hunks: hunks,
+	}
+}
+
+// Metric turns the changes to one file into a cost. The cost of a candidate
+// is the sum of the costs of every file it changed, lower is better.
+type Metric interface {
+	Name() string
+	FileCost(f *File) float64
+}
+
+// ChangedLines counts the larger of added and removed lines per file, with
+// the idea that 6 lines added and 5 removed means 5 changed and 1 added.
+type ChangedLines struct{}
+
+func (ChangedLines) Name() string { return "lines" }
+
+func (ChangedLines) FileCost(f *File) float64 {
+	return float64(max(f.Stat.Added, f.Stat.Removed))
+}
+
+// AddedRemoved counts every added and every removed line.
+type AddedRemoved struct{}
+
+func (AddedRemoved) Name() string { return "added-removed" }
+
+func (AddedRemoved) FileCost(f *File) float64 {
+	return float64(f.Stat.Added + f.Stat.Removed)
+}
+
+// Hunks counts the hunks a reviewer has to look at.
+type Hunks struct{}
+
+func (Hunks) Name() string { return "hunks" }
+
+func (Hunks) FileCost(f *File) float64 {
+	return float64(len(f.Hunks))
+}
+
+// ChangedBytes counts the bytes that differ in each block of changed lines,
+// once the bytes the old and new block start and end with are discounted.
+type ChangedBytes struct{}
+
+func (ChangedBytes) Name() string { return "bytes" }
+
+func (ChangedBytes) FileCost(f *File) float64 {
+	total := 0
+	for _, b := range ChangeBlocks(f.Lines) {
+		oldText := strings.Join(b.Old, "\n")
+		newText := strings.Join(b.New, "\n")
+
+		prefix := 0
+		for prefix < len(oldText) && prefix < len(newText) && oldText[prefix] == newText[prefix] {
+			prefix++
+		}
+
+		suffix := 0
+		for suffix < len(oldText)-prefix && suffix < len(newText)-prefix &&
+			oldText[len(oldText)-1-suffix] == newText[len(newText)-1-suffix] {
+			suffix++
+		}
+
+		total += max(len(oldText)-prefix-suffix, len(newText)-prefix-suffix)
+	}
+
+	return float64(total)
+}
+
+// FilesTouched counts 1 for every file that changed.
+type FilesTouched struct{}
+
+func (FilesTouched) Name() string { return "files" }
+
+func (FilesTouched) FileCost(f *File) float64 {
+	if f.Stat.Added+f.Stat.Removed == 0 {
+		return 0
+	}
+
+	return 1
+}
+
+// Block is a run of consecutive deleted and inserted lines, the old lines
+// being replaced by the new ones.
+type Block struct {
+	Old []string
+	New []string
+}
+
+// ChangeBlocks collects the runs of changed lines in a diff.
+func ChangeBlocks(lines []diff.Line) []Block {
+	blocks := make([]Block, 0)
+
+	var current *Block
+	for _, l := range lines {
+		if l.Op == diff.Equal {
+			current = nil
+			continue
+		}
+
+		if current == nil {
+			blocks = append(blocks, Block{})
+			current = &blocks[len(blocks)-1]
+		}
+
+		if l.Op == diff.Delete {
+			current.Old = append(current.Old, l.Text)
+		} else {
+			current.New = append(current.New, l.Text)
+		}
+	}
+
+	return blocks
+}
+
+// Term is one metric of a weighted sum.
+type Term struct {
+	Weight float64
+	Metric Metric
+}
+
+// Weighted adds up its terms, each multiplied by its weight.
+type Weighted []Term
+
+func (w Weighted) Name() string {
+	parts := make([]string, len(w))
+	for i, t := range w {
+		parts[i] = fmt.Sprintf("%s*%s", strconv.FormatFloat(t.Weight, 'f', -1, 64), t.Metric.Name())
+	}
+
+	return strings.Join(parts, "+")
+}
+
+func (w Weighted) FileCost(f *File) float64 {
+	total := 0.0
+	for _, t := range w {
+		total += t.Weight * t.Metric.FileCost(f)
+	}
+
+	return total
+}
+
+// builtins are the metrics Parse knows by name.
+var builtins = map[string]Metric{}
+
+func init() {
+	for _, m := range []Metric{
+		ChangedLines{},
+		AddedRemoved{},
+		Hunks{},
+		ChangedBytes{},
+		FilesTouched{},
+		TokenBoundaries{},
+	} {
+		builtins[m.Name()] = m
+	}
+
+	for _, c := range AllCategories {
+		builtins[string(c)] = CategoryCount{Category: c}
+	}
+}
+
+// Names lists the metrics Parse accepts, alphabetically.
+func Names() []string {
+	names := make([]string, 0, len(builtins))
+	for n := range builtins {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// Parse turns a spec into a metric. A spec is a metric name, like "lines",
+// or a weighted sum of them, like "lines+10*hunks" or "0.5*bytes+lines".
+// Metrics that need setting up, like blame, are passed in as extra and can be
+// used by name as well. Like file weights, term weights can't be negative.
+func Parse(spec string, extra ...Metric) (Metric, error) {
+	known := make(map[string]Metric, len(builtins)+len(extra))
+	for name, m := range builtins {
+		known[name] = m
+	}
+	for _, m := range extra {
+		known[m.Name()] = m
+	}
+
+	names := make([]string, 0, len(known))
+	for name := range known {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	terms := strings.Split(spec, "+")
+	if len(terms) == 1 && !strings.Contains(spec, "*") {
+		m, ok := known[strings.TrimSpace(spec)]
+		if !ok {
+			return nil, errors.Errorf("unknown metric %q, want one of %s", spec, strings.Join(names, ", "))
+		}
+
+		return m, nil
+	}
+
+	w := make(Weighted, 0, len(terms))
+	for _, term := range terms {
+		weight := 1.0
+		name := strings.TrimSpace(term)
+
+		if before, after, ok := strings.Cut(name, "*"); ok {
+			var err error
+			weight, err = strconv.ParseFloat(strings.TrimSpace(before), 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "weight of term %q", term)
+			}
+			if weight < 0 {
+				return nil, errors.Errorf("weight of term %q is negative", term)
+			}
+			name = strings.TrimSpace(after)
+		}
+
+		m, ok := known[name]
+		if !ok {
+			return nil, errors.Errorf("unknown metric %q in %q, want one of %s",
+				name, spec, strings.Join(names, ", "))
+		}
+
+		w = append(w, Term{Weight: weight, Metric: m})
+	}
+
+	return w, nil
+}
+
+// Uses reports whether m is, or is a weighted sum containing, the metric
+// called name.
+func Uses(m Metric, name string) bool {
+	if w, ok := m.(Weighted); ok {
+		for _, t := range w {
+			if Uses(t.Metric, name) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return m.Name() == name
+}
@@ -0,0 +1,76 @@
+package metric
+
+import (
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	original := []byte("int main(void) {\n  return 0;\n}\n\nint x;\nint y;\nint z;\nint a;\nint b;\nint c;\nint d;\n")
+	formatted := []byte("int main(void)\n{\n    return 0;\n}\n\nint x;\nint y;\nint z;\nint a;\nint b;\nint c;\nint  d;\n")
+	f := NewFile("src/main.c", original, formatted)
+
+	tests := []struct {
+		metric Metric
+		want   float64
+	}{
+		// 3 lines removed, 4 added
+		{metric: ChangedLines{}, want: 4},
+		{metric: AddedRemoved{}, want: 7},
+		{metric: Hunks{}, want: 2},
+		// " {\n" became "\n{\n  " in the first block, "int d;" gained a
+		// space in the second
+		{metric: ChangedBytes{}, want: 5 + 1},
+		{metric: FilesTouched{}, want: 1},
+		// a newline before "{", the indentation of "return" and the space
+		// before "d"
+		{metric: TokenBoundaries{}, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.metric.Name(), func(t *testing.T) {
+			if got := tt.metric.FileCost(f); got != tt.want {
+				t.Errorf("%s FileCost() = %v, want %v", tt.metric.Name(), got, tt.want)
+			}
+
+			// a file rebuilt from its recorded hunks costs the same
+			if got := tt.metric.FileCost(FromHunks(f.Path, f.Hunks)); got != tt.want {
+				t.Errorf("%s FileCost() of FromHunks = %v, want %v", tt.metric.Name(), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	f := NewFile("a.c", []byte("a\nb\n"), []byte("a\nc\nd\n"))
+
+	tests := []struct {
+		spec     string
+		wantName string
+		wantCost float64
+		wantErr  bool
+	}{
+		{spec: "lines", wantName: "lines", wantCost: 2},
+		{spec: "lines+10*hunks", wantName: "1*lines+10*hunks", wantCost: 12},
+		{spec: " 0.5*added-removed + files ", wantName: "0.5*added-removed+1*files", wantCost: 2.5},
+		{spec: "churn", wantErr: true},
+		{spec: "x*lines", wantErr: true},
+		{spec: "-1*lines", wantErr: true},
+		{spec: "lines+-0.5*hunks", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := Parse(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Name() != tt.wantName {
+				t.Errorf("Parse().Name() = %q, want %q", got.Name(), tt.wantName)
+			}
+			if cost := got.FileCost(f); cost != tt.wantCost {
+				t.Errorf("Parse().FileCost() = %v, want %v", cost, tt.wantCost)
+			}
+		})
+	}
+}
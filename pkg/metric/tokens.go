@@ -0,0 +1,75 @@
+package metric
+
+import (
+	"strings"
+
+	"github.com/javorszky/go-diff-clang/pkg/ctoken"
+	"github.com/javorszky/go-diff-clang/pkg/diff"
+)
+
+// TokenBoundaries counts the places between C tokens where the whitespace
+// changed: a newline inserted or removed, a line indented differently, or the
+// spacing between two tokens altered. Reflowing a long call over three lines
+// costs two boundaries rather than three changed lines. Tokens that were
+// added or removed, like a reflowed comment, count one each.
+type TokenBoundaries struct{}
+
+func (TokenBoundaries) Name() string { return "tokens" }
+
+func (TokenBoundaries) FileCost(f *File) float64 {
+	n := 0
+	for _, b := range ChangeBlocks(f.Lines) {
+		n += boundaryChanges(b)
+	}
+
+	return float64(n)
+}
+
+// boundaryChanges tokenizes both sides of a block and compares the
+// whitespace in front of every token the two sides share.
+func boundaryChanges(b Block) int {
+	oldTokens, oldTrailing := ctoken.Tokenize(blockText(b.Old))
+	newTokens, newTrailing := ctoken.Tokenize(blockText(b.New))
+
+	n := 0
+	if oldTrailing != newTrailing {
+		n++
+	}
+
+	i, j := 0, 0
+	for _, op := range diff.Strings(tokenTexts(oldTokens), tokenTexts(newTokens)) {
+		switch op {
+		case diff.Equal:
+			if oldTokens[i].Space != newTokens[j].Space {
+				n++
+			}
+			i++
+			j++
+		case diff.Delete:
+			n++
+			i++
+		case diff.Insert:
+			n++
+			j++
+		}
+	}
+
+	return n
+}
+
+func blockText(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func tokenTexts(tokens []ctoken.Token) []string {
+	out := make([]string, len(tokens))
+	for i, t := range tokens {
+		out[i] = t.Text
+	}
+
+	return out
+}
@@ -0,0 +1,103 @@
+package metric
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/javorszky/go-diff-clang/pkg/corpus"
+	"github.com/pkg/errors"
+)
+
+// WeightRule multiplies the cost of the files matching Glob by Weight. A
+// weight of 0 keeps the files in the corpus but stops them counting.
+type WeightRule struct {
+	// Glob is relative to the corpus root, "**" matches any number of
+	// directories.
+	Glob   string
+	Weight float64
+}
+
+// FileWeights is an ordered list of rules. The last rule matching a file
+// decides its weight, files no rule matches weigh 1.
+type FileWeights []WeightRule
+
+// Weight returns the multiplier for the file at path.
+func (w FileWeights) Weight(path string) float64 {
+	weight := 1.0
+	for _, r := range w {
+		if corpus.MatchGlob(r.Glob, path) {
+			weight = r.Weight
+		}
+	}
+
+	return weight
+}
+
+// ParseWeightRule parses a rule written as glob=weight, like
+// "src/nodejs/**=0".
+func ParseWeightRule(s string) (WeightRule, error) {
+	glob, weight, ok := strings.Cut(s, "=")
+	if !ok {
+		return WeightRule{}, errors.Errorf("weight rule %q is not glob=weight", s)
+	}
+
+	w, err := strconv.ParseFloat(strings.TrimSpace(weight), 64)
+	if err != nil {
+		return WeightRule{}, errors.Wrapf(err, "weight of rule %q", s)
+	}
+
+	if w < 0 {
+		return WeightRule{}, errors.Errorf("weight of rule %q is negative", s)
+	}
+
+	return WeightRule{Glob: strings.TrimSpace(glob), Weight: w}, nil
+}
+
+// Total is the cost of a candidate with and without file weights applied.
+type Total struct {
+	Raw      int `json:"raw"`
+	Weighted int `json:"weighted"`
+}
+
+// Cost is the cost of one file, before and after its weight.
+type Cost struct {
+	Path     string
+	Raw      float64
+	Weighted float64
+}
+
+// Costs works out the cost of every file.
+func Costs(m Metric, weights FileWeights, files []*File) []Cost {
+	costs := make([]Cost, len(files))
+	for i, f := range files {
+		raw := m.FileCost(f)
+		costs[i] = Cost{
+			Path:     f.Path,
+			Raw:      raw,
+			Weighted: raw * weights.Weight(f.Path),
+		}
+	}
+
+	return costs
+}
+
+// SumCosts adds up file costs, rounding both totals to whole numbers.
+func SumCosts(costs []Cost) Total {
+	raw, weighted := 0.0, 0.0
+	for _, c := range costs {
+		raw += c.Raw
+		weighted += c.Weighted
+	}
+
+	return Total{
+		Raw:      int(math.Round(raw)),
+		Weighted: int(math.Round(weighted)),
+	}
+}
+
+// Sum adds up the cost of every file, once as is and once multiplied by the
+// file's weight, and rounds both to whole numbers.
+func Sum(m Metric, weights FileWeights, files []*File) Total {
+	return SumCosts(Costs(m, weights, files))
+}
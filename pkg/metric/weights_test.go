@@ -0,0 +1,52 @@
+package metric
+
+import (
+	"testing"
+)
+
+func TestSum(t *testing.T) {
+	files := []*File{
+		NewFile("src/nxt_conf.c", []byte("a\n"), []byte("b\n")),
+		NewFile("src/nodejs/unit-http/nxt_napi.h", []byte("a\nb\n"), []byte("c\nd\n")),
+		NewFile("src/test/nxt_lvlhsh_test.c", []byte("a\nb\nc\n"), []byte("x\ny\nz\n")),
+	}
+
+	var weights FileWeights
+	for _, r := range []string{"src/**/*_test.c=0.5", "src/nodejs/**=0", "src/test/**=2"} {
+		rule, err := ParseWeightRule(r)
+		if err != nil {
+			t.Fatalf("ParseWeightRule(%q) error = %v", r, err)
+		}
+		weights = append(weights, rule)
+	}
+
+	got := Sum(ChangedLines{}, weights, files)
+	want := Total{Raw: 1 + 2 + 3, Weighted: 1 + 0 + 3*2}
+	if got != want {
+		t.Errorf("Sum() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseWeightRule(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    WeightRule
+		wantErr bool
+	}{
+		{in: "src/nodejs/**=0", want: WeightRule{Glob: "src/nodejs/**", Weight: 0}},
+		{in: "src/*.h = 1.5", want: WeightRule{Glob: "src/*.h", Weight: 1.5}},
+		{in: "src/nodejs/**", wantErr: true},
+		{in: "src/**=-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseWeightRule(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWeightRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseWeightRule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
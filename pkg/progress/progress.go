@@ -0,0 +1,157 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/javorszky/go-diff-clang/pkg/events"
+)
+
+// plainInterval is how often a progress line is printed when the output is
+// not a terminal.
+const plainInterval = 30 * time.Second
+
+// Display is an events.Sink that renders the progress of a run. On a terminal
+// it keeps redrawing a single status line, otherwise it prints a plain line
+// every plainInterval.
+type Display struct {
+	mu sync.Mutex
+
+	w        io.Writer
+	tty      bool
+	total    int
+	passes   int
+	interval time.Duration
+
+	pass      int
+	option    string
+	evaluated int
+	best      int
+	lastPrint time.Time
+	width     int
+
+	// scored and spent only cover the evaluations clang-format accepted,
+	// the ones it rejects fail fast and would make the ETA too optimistic.
+	scored int
+	spent  time.Duration
+}
+
+// New returns a Display writing to f that expects total evaluations across
+// passes passes.
+func New(f *os.File, total, passes int) *Display {
+	return &Display{
+		w:        f,
+		tty:      isTerminal(f),
+		total:    total,
+		passes:   passes,
+		interval: plainInterval,
+		best:     math.MaxInt32,
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+func (d *Display) Emit(e events.Event) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch ev := e.(type) {
+	case events.EvaluationStarted:
+		d.pass = ev.Pass
+		d.option = ev.Option
+	case events.EvaluationFinished:
+		d.evaluated++
+		if ev.ExitCode != 0 {
+			// a rejected value has no cost
+			break
+		}
+
+		d.scored++
+		d.spent += time.Duration(ev.DurationMS) * time.Millisecond
+		if ev.Cost < d.best {
+			d.best = ev.Cost
+		}
+	default:
+		return
+	}
+
+	d.render(false)
+}
+
+// Finish prints the final state and moves past the status line.
+func (d *Display) Finish() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.render(true)
+	if d.tty {
+		fmt.Fprintln(d.w)
+	}
+}
+
+func (d *Display) render(force bool) {
+	now := time.Now()
+	if !d.tty && !force && now.Sub(d.lastPrint) < d.interval {
+		return
+	}
+	d.lastPrint = now
+
+	line := d.line()
+
+	if !d.tty {
+		fmt.Fprintln(d.w, line)
+		return
+	}
+
+	// Pad with spaces so a shorter line fully covers the previous one.
+	pad := ""
+	if len(line) < d.width {
+		pad = strings.Repeat(" ", d.width-len(line))
+	}
+	d.width = len(line)
+
+	fmt.Fprintf(d.w, "\r%s%s", line, pad)
+}
+
+func (d *Display) line() string {
+	best := "-"
+	if d.best != math.MaxInt32 {
+		best = fmt.Sprintf("%d", d.best)
+	}
+
+	avg := time.Duration(0)
+	if d.scored > 0 {
+		avg = d.spent / time.Duration(d.scored)
+	}
+
+	remaining := d.total - d.evaluated
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	eta := "-"
+	if d.scored > 0 {
+		eta = (avg * time.Duration(remaining)).Round(time.Second).String()
+	}
+
+	return fmt.Sprintf("pass %d/%d | %s | %d/%d evaluated | best %s | avg %s | eta %s",
+		d.pass, d.passes,
+		d.option,
+		d.evaluated, d.total,
+		best,
+		avg.Round(time.Millisecond),
+		eta,
+	)
+}
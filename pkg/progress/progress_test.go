@@ -0,0 +1,66 @@
+package progress
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/javorszky/go-diff-clang/pkg/events"
+)
+
+func TestDisplay_plain(t *testing.T) {
+	buf := &bytes.Buffer{}
+	d := &Display{
+		w:        buf,
+		total:    10,
+		passes:   3,
+		interval: time.Hour,
+		best:     math.MaxInt32,
+	}
+
+	d.Emit(events.EvaluationStarted{Pass: 1, Option: "IndentWidth", Value: "2"})
+	d.Emit(events.EvaluationFinished{Pass: 1, Option: "IndentWidth", Value: "2", Cost: 300, DurationMS: 2000})
+
+	// The first line is printed straight away, later ones wait for the
+	// interval.
+	want := "pass 1/3 | IndentWidth | 0/10 evaluated | best - | avg 0s | eta -\n"
+	if buf.String() != want {
+		t.Fatalf("after first events got %q, want %q", buf.String(), want)
+	}
+
+	d.Emit(events.EvaluationStarted{Pass: 1, Option: "IndentWidth", Value: "4"})
+	d.Emit(events.EvaluationFinished{Pass: 1, Option: "IndentWidth", Value: "4", Cost: 200, DurationMS: 4000})
+	d.Finish()
+
+	want += "pass 1/3 | IndentWidth | 2/10 evaluated | best 200 | avg 3s | eta 24s\n"
+	if buf.String() != want {
+		t.Errorf("after finish got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestDisplay_rejected(t *testing.T) {
+	buf := &bytes.Buffer{}
+	d := &Display{
+		w:        buf,
+		total:    10,
+		passes:   3,
+		interval: time.Hour,
+		best:     math.MaxInt32,
+	}
+
+	d.Emit(events.EvaluationStarted{Pass: 1, Option: "IndentWidth", Value: "4"})
+	d.Emit(events.EvaluationFinished{Pass: 1, Option: "IndentWidth", Value: "4", Cost: 200, DurationMS: 4000})
+	d.Emit(events.EvaluationStarted{Pass: 1, Option: "IndentWidth", Value: "8"})
+	d.Emit(events.EvaluationFinished{Pass: 1, Option: "IndentWidth", Value: "8", ExitCode: 1, DurationMS: 10})
+	d.Finish()
+
+	// the rejected value counts as evaluated, but not towards the best cost
+	// or the average
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := "pass 1/3 | IndentWidth | 2/10 evaluated | best 200 | avg 4s | eta 32s"
+	if got := lines[len(lines)-1]; got != want {
+		t.Errorf("after a rejected value got %q, want %q", got, want)
+	}
+}
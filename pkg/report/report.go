@@ -0,0 +1,214 @@
+package report
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	clangformat "github.com/javorszky/go-diff-clang/pkg/clang-format"
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+	"github.com/pkg/errors"
+)
+
+//go:embed report.html.tmpl
+var reportTemplate string
+
+var tmpl = template.Must(template.New("report").Funcs(template.FuncMap{
+	"diffLines": diffLines,
+}).Parse(reportTemplate))
+
+// view is what the template renders.
+type view struct {
+	Generated   string
+	Metric      string
+	Total       metric.Total
+	Format      string
+	Evaluations int
+	Failed      int
+	Duration    string
+	Corpora     []clangformat.CorpusResult
+	Passes      []passRow
+	Options     []optionRow
+	Identical   []string
+	Tied        []string
+	Samples     []Sample
+}
+
+type passRow struct {
+	Pass        int
+	Total       metric.Total
+	Improvement int
+	Irrelevant  int
+}
+
+type optionRow struct {
+	Option     string
+	Final      string
+	Irrelevant bool
+	Passes     []optionPass
+}
+
+type optionPass struct {
+	Pass   int
+	Values []valueCell
+}
+
+type valueCell struct {
+	Value  string
+	Cost   int
+	Winner bool
+	Failed bool
+}
+
+type diffLine struct {
+	Class string
+	Text  string
+}
+
+// Write renders a single page HTML report of the run, with no outside
+// resources, so it can be attached anywhere.
+func Write(w io.Writer, run *clangformat.Run, samples []Sample) error {
+	err := tmpl.Execute(w, newView(run, samples))
+	if err != nil {
+		return errors.Wrap(err, "rendering report")
+	}
+
+	return nil
+}
+
+func newView(run *clangformat.Run, samples []Sample) view {
+	v := view{
+		Generated:   time.Now().Format(time.RFC1123),
+		Metric:      run.Metric,
+		Total:       run.Total,
+		Format:      run.Format.String(),
+		Evaluations: len(run.Evaluations),
+		Corpora:     run.Corpora,
+		Samples:     samples,
+	}
+
+	var spent time.Duration
+	for _, e := range run.Evaluations {
+		spent += time.Duration(e.DurationMS) * time.Millisecond
+		if e.Failed() {
+			v.Failed++
+		}
+	}
+	v.Duration = spent.Round(time.Second).String()
+
+	// the first candidate scored is where the search started
+	previous := 0
+	for _, e := range run.Evaluations {
+		if e.Pass == 1 && !e.Failed() {
+			previous = e.Total.Weighted
+			break
+		}
+	}
+
+	for _, p := range run.Passes {
+		v.Passes = append(v.Passes, passRow{
+			Pass:        p.Pass,
+			Total:       p.Total,
+			Improvement: previous - p.Total.Weighted,
+			Irrelevant:  len(p.Irrelevant),
+		})
+		previous = p.Total.Weighted
+	}
+
+	v.Options = optionRows(run)
+	for _, d := range run.FinalDecisions() {
+		switch {
+		case d.Identical:
+			v.Identical = append(v.Identical, d.Option)
+		case d.Irrelevant:
+			v.Tied = append(v.Tied, d.Option)
+		}
+	}
+
+	return v
+}
+
+// optionRows lists every value tried for every option, pass by pass, in the
+// order they were evaluated.
+func optionRows(run *clangformat.Run) []optionRow {
+	type key struct {
+		pass   int
+		option string
+	}
+
+	winners := make(map[key]string)
+	for _, d := range run.Decisions {
+		winners[key{d.Pass, d.Option}] = d.Value
+	}
+
+	final := make(map[string]clangformat.Decision)
+	for _, d := range run.FinalDecisions() {
+		final[d.Option] = d
+	}
+
+	rows := make(map[string]*optionRow)
+	for _, e := range run.Evaluations {
+		// the reference style is not an option
+		if e.Option == "" {
+			continue
+		}
+
+		row, ok := rows[e.Option]
+		if !ok {
+			row = &optionRow{
+				Option:     e.Option,
+				Final:      run.Format[e.Option],
+				Irrelevant: final[e.Option].Irrelevant,
+			}
+			rows[e.Option] = row
+		}
+
+		if len(row.Passes) == 0 || row.Passes[len(row.Passes)-1].Pass != e.Pass {
+			row.Passes = append(row.Passes, optionPass{Pass: e.Pass})
+		}
+
+		p := &row.Passes[len(row.Passes)-1]
+		p.Values = append(p.Values, valueCell{
+			Value:  e.Value,
+			Cost:   e.Total.Weighted,
+			Winner: winners[key{e.Pass, e.Option}] == e.Value,
+			Failed: e.Failed(),
+		})
+	}
+
+	out := make([]optionRow, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, *row)
+	}
+	slices.SortFunc(out, func(a, b optionRow) int {
+		return strings.Compare(a.Option, b.Option)
+	})
+
+	return out
+}
+
+func diffLines(text string) []diffLine {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+
+	out := make([]diffLine, len(lines))
+	for i, l := range lines {
+		class := ""
+		switch {
+		case strings.HasPrefix(l, "+++"), strings.HasPrefix(l, "---"):
+			class = "file"
+		case strings.HasPrefix(l, "@@"):
+			class = "hunk"
+		case strings.HasPrefix(l, "+"):
+			class = "add"
+		case strings.HasPrefix(l, "-"):
+			class = "del"
+		}
+
+		out[i] = diffLine{Class: class, Text: l}
+	}
+
+	return out
+}
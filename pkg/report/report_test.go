@@ -0,0 +1,64 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	clangformat "github.com/javorszky/go-diff-clang/pkg/clang-format"
+	"github.com/javorszky/go-diff-clang/pkg/metric"
+)
+
+func TestWrite(t *testing.T) {
+	run := &clangformat.Run{
+		Metric: "lines",
+		Format: clangformat.ClangFormat{"IndentWidth": "4", "UseTab": "Never"},
+		Total:  metric.Total{Raw: 8, Weighted: 8},
+		Evaluations: []clangformat.Evaluation{
+			{Pass: 1, Option: "IndentWidth", Value: "2", Total: metric.Total{Raw: 12, Weighted: 12}},
+			{Pass: 1, Option: "IndentWidth", Value: "4", Total: metric.Total{Raw: 8, Weighted: 8}},
+			{Pass: 1, Option: "IndentWidth", Value: "8", ExitCode: 1},
+			{Pass: 1, Option: "UseTab", Value: "Never", Total: metric.Total{Raw: 8, Weighted: 8}},
+			{Pass: 1, Option: "UseTab", Value: "Always", Total: metric.Total{Raw: 8, Weighted: 8}},
+		},
+		Decisions: []clangformat.Decision{
+			{Pass: 1, Option: "IndentWidth", Value: "4", Costs: map[string]int{"2": 12, "4": 8}},
+			{Pass: 1, Option: "UseTab", Value: "Never", Costs: map[string]int{"Never": 8, "Always": 8}, Irrelevant: true,
+				Identical: true},
+		},
+		Passes: []clangformat.PassResult{
+			{Pass: 1, Total: metric.Total{Raw: 8, Weighted: 8}, Irrelevant: []string{"UseTab"}},
+		},
+	}
+	samples := []Sample{{
+		Option: "IndentWidth", Winner: "4", WinnerCost: 8, RunnerUp: "2", RunnerUpCost: 12,
+		Files: []SampleFile{{Path: "src/a.c", Added: 1, Removed: 1,
+			Diff: "--- a/src/a.c\n+++ b/src/a.c\n@@ -1 +1 @@\n-    x <y>;\n+  x <y>;\n"}},
+		FilesDiffering: 1,
+	}}
+
+	buf := bytes.Buffer{}
+	if err := Write(&buf, run, samples); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	got := buf.String()
+	for _, want := range []string{
+		"<strong>8</strong>",
+		`<span class="value winner">4=8</span>`,
+		`<span class="value failed">8</span>`,
+		"<li>UseTab</li>",
+		"IndentWidth: 4 (8) over 2 (12)",
+		`<span class="del">-    x &lt;y&gt;;</span>`,
+		// the first candidate cost 12, the pass ended at 8
+		`<td class="num">4</td>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Write() output does not contain %q", want)
+		}
+	}
+
+	if strings.Contains(got, "http") {
+		t.Errorf("Write() output refers to outside resources")
+	}
+}
@@ -0,0 +1,154 @@
+package report
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	clangformat "github.com/javorszky/go-diff-clang/pkg/clang-format"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxSampleFiles is how many of the files that differ a sample shows,
+	// the ones with the most changed lines first.
+	maxSampleFiles = 3
+
+	// maxSampleLines cuts the diff of a file short.
+	maxSampleLines = 80
+)
+
+// Sample shows what picking the runner-up value of an option instead of the
+// winner would have done to the formatted code.
+type Sample struct {
+	Option       string
+	Winner       string
+	WinnerCost   int
+	RunnerUp     string
+	RunnerUpCost int
+
+	// Files holds the diffs from the winner's output to the runner-up's,
+	// FilesDiffering how many files differ in all.
+	Files          []SampleFile
+	FilesDiffering int
+}
+
+// SampleFile is the diff of one file, possibly cut short.
+type SampleFile struct {
+	Corpus    string
+	Path      string
+	Added     int
+	Removed   int
+	Diff      string
+	Truncated bool
+}
+
+// Samples formats the corpora with the final config and, for up to n of the
+// closest decisions, with the runner-up value instead, and keeps the files
+// where the two differ. Decisions where every value cost the same are left
+// out, as are runner-ups that format the code exactly like the winner.
+func Samples(ctx context.Context, cfg clangformat.Config, run *clangformat.Run, n int) ([]Sample, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	type candidate struct {
+		decision clangformat.Decision
+		runnerUp string
+		cost     int
+	}
+
+	candidates := make([]candidate, 0)
+	for _, d := range run.FinalDecisions() {
+		if d.Irrelevant {
+			continue
+		}
+
+		value, cost, ok := d.RunnerUp()
+		if !ok {
+			continue
+		}
+
+		candidates = append(candidates, candidate{decision: d, runnerUp: value, cost: cost})
+	}
+
+	// the closest calls first, they are the ones worth a second look
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		return cmp.Compare(a.cost-a.decision.Costs[a.decision.Value], b.cost-b.decision.Costs[b.decision.Value])
+	})
+
+	winner, err := clangformat.Format(ctx, cfg, run.Format)
+	if err != nil {
+		return nil, errors.Wrap(err, "formatting with the final config")
+	}
+
+	samples := make([]Sample, 0, n)
+	for i, c := range candidates {
+		if len(samples) == n || i == 3*n {
+			break
+		}
+
+		slog.Info("formatting sample", "option", c.decision.Option, "value", c.runnerUp)
+
+		alternative := maps.Clone(run.Format)
+		alternative[c.decision.Option] = c.runnerUp
+
+		formatted, err := clangformat.Format(ctx, cfg, alternative)
+		if err != nil {
+			return nil, errors.Wrapf(err, "formatting with %s: %s", c.decision.Option, c.runnerUp)
+		}
+
+		diffs := clangformat.DiffFormatted(winner, formatted)
+		if len(diffs) == 0 {
+			continue
+		}
+
+		samples = append(samples, Sample{
+			Option:         c.decision.Option,
+			Winner:         c.decision.Value,
+			WinnerCost:     c.decision.Costs[c.decision.Value],
+			RunnerUp:       c.runnerUp,
+			RunnerUpCost:   c.cost,
+			Files:          sampleFiles(diffs),
+			FilesDiffering: len(diffs),
+		})
+	}
+
+	return samples, nil
+}
+
+// sampleFiles keeps the files with the biggest differences and cuts their
+// diffs short.
+func sampleFiles(diffs []clangformat.FileDiff) []SampleFile {
+	slices.SortStableFunc(diffs, func(a, b clangformat.FileDiff) int {
+		return cmp.Compare(b.Size(), a.Size())
+	})
+
+	files := make([]SampleFile, 0, maxSampleFiles)
+	for _, d := range diffs[:min(len(diffs), maxSampleFiles)] {
+		text, truncated := truncateLines(d.Unified(), maxSampleLines)
+
+		files = append(files, SampleFile{
+			Corpus:    d.Corpus,
+			Path:      d.Path,
+			Added:     d.Stat.Added,
+			Removed:   d.Stat.Removed,
+			Diff:      text,
+			Truncated: truncated,
+		})
+	}
+
+	return files
+}
+
+func truncateLines(text string, n int) (string, bool) {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) <= n {
+		return text, false
+	}
+
+	return strings.Join(lines[:n], ""), true
+}